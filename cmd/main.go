@@ -1,13 +1,16 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/prajapatiomkar/wave-server/config"
+	"github.com/prajapatiomkar/wave-server/internal/auth"
+	"github.com/prajapatiomkar/wave-server/internal/broker"
 	"github.com/prajapatiomkar/wave-server/internal/handlers"
 	"github.com/prajapatiomkar/wave-server/internal/middleware"
 	"github.com/prajapatiomkar/wave-server/internal/repositories"
@@ -16,43 +19,104 @@ import (
 )
 
 func main() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found")
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	printConfig := flag.Bool("print-config", false, "print the configuration with secrets redacted and exit")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal("Invalid configuration:\n", err)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(); err != nil {
+			log.Fatal("Failed to print configuration:", err)
+		}
+		return
+	}
+
+	// Schema management subcommands
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		runMigrate(cfg, args[1:])
+		return
 	}
 
 	// Connect to database
-	config.ConnectDatabase()
+	config.ConnectDatabase(cfg.Database)
+	migrateOnStart(cfg)
 
 	// Set Gin mode
-	if os.Getenv("ENV") == "production" {
+	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(config.GetDB())
 	messageRepo := repositories.NewMessageRepository(config.GetDB())
+	roomRepo := repositories.NewRoomRepository(config.GetDB())
+	roomMemberRepo := repositories.NewRoomMemberRepository(config.GetDB())
+	reactionRepo := repositories.NewReactionRepository(config.GetDB())
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(config.GetDB())
+	revocationRepo := repositories.NewRevocationRepository(config.GetDB())
+	sessionRepo := repositories.NewSessionRepository(config.GetDB())
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo)
-	messageService := services.NewMessageService(messageRepo, userRepo)
+	revocationService := services.NewRevocationService(revocationRepo)
+	if err := revocationService.Start(); err != nil {
+		log.Fatal("Failed to load revoked tokens:", err)
+	}
+	tokenSigner, err := auth.NewTokenSigner(cfg.Auth)
+	if err != nil {
+		log.Fatal("Invalid signing keys:", err)
+	}
+	tokenVerifier, err := auth.NewTokenVerifier(cfg.Auth, revocationService)
+	if err != nil {
+		log.Fatal("Invalid signing keys:", err)
+	}
+	authService := services.NewAuthService(userRepo, refreshTokenRepo, sessionRepo, revocationService, tokenSigner, cfg.Auth)
+	roomService := services.NewRoomService(roomRepo, roomMemberRepo, userRepo)
+	presenceService := services.NewPresenceService(userRepo, roomRepo)
+	conversationService := services.NewConversationService(messageRepo, userRepo)
+	messageService := services.NewMessageService(messageRepo, userRepo, reactionRepo, roomService)
 
 	// Initialize WebSocket hub
-	hub := websocket.NewHub(messageService)
+	hubBroker, err := newBroker(cfg)
+	if err != nil {
+		log.Fatal("Failed to start broker:", err)
+	}
+	defer hubBroker.Close()
+
+	hubOptions := websocket.Options{
+		Shards:    cfg.Hub.Shards,
+		Workers:   cfg.Hub.Workers,
+		QueueSize: cfg.Hub.QueueSize,
+	}
+	hubOptions.SlowConsumer, err = websocket.ParseSlowConsumerPolicy(cfg.Hub.SlowConsumer)
+	if err != nil {
+		log.Fatal("Invalid hub options:", err)
+	}
+
+	hub := websocket.NewHub(messageService, presenceService, hubBroker, hubOptions)
 	go hub.Run()
+	revocationService.OnRevoke(hub.CloseSession)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
-	wsHandler := handlers.NewWebSocketHandler(hub)
-	messageHandler := handlers.NewMessageHandler(messageService)
+	wsHandler := handlers.NewWebSocketHandler(hub, roomService, tokenVerifier)
+	messageHandler := handlers.NewMessageHandler(messageService, hub)
+	conversationHandler := handlers.NewConversationHandler(conversationService)
+	presenceHandler := handlers.NewPresenceHandler(presenceService)
+	roomHandler := handlers.NewRoomHandler(roomService, messageService, hub)
+	sessionHandler := handlers.NewSessionHandler(authService, hub)
 
 	// Initialize Gin router
 	router := gin.Default()
 
 	// Configure CORS
 	corsConfig := cors.Config{
-		AllowOrigins:     []string{os.Getenv("FRONTEND_URL")},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowOrigins:     []string{cfg.FrontendURL},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
 		AllowCredentials: true,
 		AllowWebSockets:  true,
@@ -64,6 +128,19 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok", "message": "Chat API is running"})
 	})
 
+	// Public keys for services that verify our access tokens
+	router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, tokenVerifier.JWKS())
+	})
+
+	// WebSocket metrics
+	router.GET("/debug/vars", func(c *gin.Context) {
+		c.JSON(200, websocket.Metrics())
+	})
+
+	authMiddleware := middleware.AuthMiddleware(tokenVerifier)
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
@@ -72,6 +149,9 @@ func main() {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authMiddleware, authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, authHandler.LogoutAll)
 		}
 
 		// WebSocket route (handles auth internally)
@@ -79,21 +159,73 @@ func main() {
 
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(authMiddleware)
 		{
 			protected.GET("/me", authHandler.GetMe)
-			protected.GET("/messages/:room_id", messageHandler.GetMessageHistory)
+			protected.GET("/sessions", sessionHandler.ListSessions)
+			protected.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			protected.GET("/conversations", conversationHandler.ListConversations)
+			protected.GET("/presence", presenceHandler.GetPresence)
+			// Gin needs one wildcard name per path segment, so :id is the
+			// room for history and the message everywhere else.
+			protected.GET("/messages/:id", messageHandler.GetMessageHistory)
+			protected.GET("/messages/:id/thread", messageHandler.GetThread)
+			protected.PATCH("/messages/:id", messageHandler.EditMessage)
+			protected.DELETE("/messages/:id", messageHandler.DeleteMessage)
+			protected.POST("/messages/:id/reactions", messageHandler.AddReaction)
+			protected.DELETE("/messages/:id/reactions/:emoji", messageHandler.RemoveReaction)
+
+			rooms := protected.Group("/rooms")
+			{
+				rooms.POST("", roomHandler.CreateRoom)
+				rooms.GET("", roomHandler.ListRooms)
+				rooms.GET("/:room_id", roomHandler.GetRoom)
+				rooms.PATCH("/:room_id", roomHandler.UpdateRoom)
+				rooms.POST("/:room_id/archive", roomHandler.ArchiveRoom)
+				rooms.POST("/:room_id/join", roomHandler.JoinRoom)
+				rooms.POST("/:room_id/leave", roomHandler.LeaveRoom)
+				rooms.POST("/:room_id/read", roomHandler.MarkRead)
+				rooms.GET("/:room_id/members", roomHandler.ListMembers)
+				rooms.POST("/:room_id/members", roomHandler.InviteMember)
+				rooms.DELETE("/:room_id/members/:user_id", roomHandler.KickMember)
+			}
+
+			dms := protected.Group("/dms")
+			{
+				dms.GET("", roomHandler.ListDirectMessages)
+				dms.POST("/:user_id", roomHandler.OpenDirectMessage)
+			}
 		}
 	}
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	log.Printf("Server starting on port %s", cfg.Port)
+	if err := router.Run(":" + cfg.Port); err != nil {
+		log.Fatal("Failed to start server:", err)
 	}
+}
 
-	log.Printf("Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+// newBroker selects the hub backplane. The in-memory broker only reaches
+// clients on this node; postgres and redis fan out across instances.
+func newBroker(cfg *config.Config) (websocket.Broker, error) {
+	switch cfg.Broker.Kind {
+	case "memory":
+		return broker.NewMemoryBroker(), nil
+	case "postgres":
+		pgBroker, err := broker.NewPostgresBroker(config.GetDB(), cfg.Database.DSN())
+		if err != nil {
+			return nil, err
+		}
+		log.Println("✅ Using Postgres broker")
+		return pgBroker, nil
+	case "redis":
+		redisBroker, err := broker.NewRedisBroker(cfg.Broker.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("✅ Using Redis broker")
+		return redisBroker, nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", cfg.Broker.Kind)
 	}
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/prajapatiomkar/wave-server/config"
+	"github.com/prajapatiomkar/wave-server/internal/migrations"
+)
+
+const migrateUsage = "usage: wave-server migrate up|down [steps]|status"
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	config.ConnectDatabase(cfg.Database)
+	migrator, err := migrations.New(config.GetDB())
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		log.Printf("✅ Applied %d migration(s)", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		if err != nil {
+			log.Fatal("Failed to roll back database:", err)
+		}
+		log.Printf("✅ Rolled back %d migration(s)", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stdout, "%04d  %-40s %s\n", status.Version, status.Name, applied)
+		}
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+// migrateOnStart applies pending migrations when database.auto_migrate is
+// set. Otherwise the schema is left to `migrate up` and the server only warns.
+func migrateOnStart(cfg *config.Config) {
+	migrator, err := migrations.New(config.GetDB())
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	if cfg.Database.AutoMigrate {
+		if _, err := migrator.Up(); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		log.Println("✅ Database migration completed")
+		return
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		log.Fatal("Failed to read migration status:", err)
+	}
+	if pending > 0 {
+		log.Printf("Warning: %d pending migration(s); run `wave-server migrate up`", pending)
+	}
+}
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Config is the complete server configuration. Each field is read from, in
+// increasing order of precedence: its default, the optional YAML or TOML
+// config file, the .env file and the process environment.
+//
+// Field tags: `config` is the key in the config file, `env` the environment
+// variable, `default` the fallback value. `required` fields must be set and
+// `secret` fields are redacted by Redacted.
+type Config struct {
+	Env         string `config:"env" env:"ENV" default:"development"`
+	Port        string `config:"port" env:"PORT" default:"8080"`
+	FrontendURL string `config:"frontend_url" env:"FRONTEND_URL" required:"true"`
+
+	Database DatabaseConfig `config:"database"`
+	Auth     AuthConfig     `config:"auth"`
+	Broker   BrokerConfig   `config:"broker"`
+	Hub      HubConfig      `config:"hub"`
+}
+
+type DatabaseConfig struct {
+	Host     string `config:"host" env:"DB_HOST" default:"localhost"`
+	Port     string `config:"port" env:"DB_PORT" default:"5432"`
+	User     string `config:"user" env:"DB_USER" required:"true"`
+	Password string `config:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `config:"name" env:"DB_NAME" required:"true"`
+	SSLMode  string `config:"sslmode" env:"DB_SSLMODE" default:"require"`
+	// AutoMigrate applies pending migrations at startup.
+	AutoMigrate bool `config:"auto_migrate" env:"DB_AUTO_MIGRATE" default:"false"`
+}
+
+type AuthConfig struct {
+	// JWTSecret is a single HS256 key with key ID "default". JWTKeys lists
+	// further HS256 keys as "kid=secret,kid=secret". JWTPrivateKeys lists
+	// RSA (RS256) or Ed25519 (EdDSA) private keys as "kid=path.pem", and
+	// JWTPublicKeys retired public keys that should still verify. New tokens
+	// are signed with JWTKeyID; every listed key verifies, which is how keys
+	// are rotated.
+	JWTSecret      string `config:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	JWTKeys        string `config:"jwt_keys" env:"JWT_KEYS" secret:"true"`
+	JWTPrivateKeys string `config:"jwt_private_keys" env:"JWT_PRIVATE_KEYS"`
+	JWTPublicKeys  string `config:"jwt_public_keys" env:"JWT_PUBLIC_KEYS"`
+	JWTKeyID       string `config:"jwt_key_id" env:"JWT_KEY_ID" default:"default"`
+
+	Issuer   string        `config:"issuer" env:"JWT_ISSUER" default:"wave-server"`
+	Audience string        `config:"audience" env:"JWT_AUDIENCE" default:"wave-api"`
+	Leeway   time.Duration `config:"leeway" env:"JWT_LEEWAY" default:"30s"`
+
+	// TokenTTL is the lifetime of access tokens; clients renew them with
+	// refresh tokens, which live for RefreshTTL.
+	TokenTTL   time.Duration `config:"token_ttl" env:"JWT_TOKEN_TTL" default:"15m"`
+	RefreshTTL time.Duration `config:"refresh_ttl" env:"REFRESH_TOKEN_TTL" default:"720h"`
+}
+
+type BrokerConfig struct {
+	// Kind is memory, postgres or redis.
+	Kind     string `config:"kind" env:"BROKER" default:"memory"`
+	RedisURL string `config:"redis_url" env:"REDIS_URL" secret:"true"`
+}
+
+type HubConfig struct {
+	Shards       int    `config:"shards" env:"HUB_SHARDS" default:"16"`
+	Workers      int    `config:"workers" env:"HUB_WORKERS" default:"32"`
+	QueueSize    int    `config:"queue_size" env:"HUB_QUEUE_SIZE" default:"256"`
+	SlowConsumer string `config:"slow_consumer" env:"SLOW_CONSUMER_POLICY" default:"disconnect"`
+}
+
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
+	)
+}
+
+// HMACKeys returns the HS256 secrets by key ID.
+func (c AuthConfig) HMACKeys() (map[string][]byte, error) {
+	entries, err := parseKeyList(c.JWTKeys, "auth.jwt_keys")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte, len(entries)+1)
+	if c.JWTSecret != "" {
+		keys["default"] = []byte(c.JWTSecret)
+	}
+	for kid, secret := range entries {
+		if _, dup := keys[kid]; dup {
+			return nil, fmt.Errorf("auth.jwt_keys: key ID %q is defined twice", kid)
+		}
+		keys[kid] = []byte(secret)
+	}
+	return keys, nil
+}
+
+// PrivateKeyFiles returns the PEM paths of the asymmetric signing keys by
+// key ID.
+func (c AuthConfig) PrivateKeyFiles() (map[string]string, error) {
+	return parseKeyList(c.JWTPrivateKeys, "auth.jwt_private_keys")
+}
+
+// PublicKeyFiles returns the PEM paths of verify-only public keys by key ID.
+func (c AuthConfig) PublicKeyFiles() (map[string]string, error) {
+	return parseKeyList(c.JWTPublicKeys, "auth.jwt_public_keys")
+}
+
+func (c AuthConfig) validateKeys() error {
+	hmacKeys, err := c.HMACKeys()
+	if err != nil {
+		return err
+	}
+	privateKeys, err := c.PrivateKeyFiles()
+	if err != nil {
+		return err
+	}
+	publicKeys, err := c.PublicKeyFiles()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, keys := range []map[string]string{privateKeys, publicKeys} {
+		for kid := range keys {
+			if _, ok := hmacKeys[kid]; ok || seen[kid] {
+				return fmt.Errorf("auth: key ID %q is defined twice", kid)
+			}
+			seen[kid] = true
+		}
+	}
+
+	if len(hmacKeys) == 0 && len(privateKeys) == 0 {
+		return errors.New("auth.jwt_secret (JWT_SECRET), auth.jwt_keys (JWT_KEYS) or auth.jwt_private_keys (JWT_PRIVATE_KEYS) is required")
+	}
+
+	_, isHMAC := hmacKeys[c.JWTKeyID]
+	_, isPrivate := privateKeys[c.JWTKeyID]
+	if !isHMAC && !isPrivate {
+		return fmt.Errorf("auth.jwt_key_id: no signing key with ID %q", c.JWTKeyID)
+	}
+	return nil
+}
+
+// parseKeyList parses "kid=value,kid=value".
+func parseKeyList(list, key string) (map[string]string, error) {
+	entries := make(map[string]string)
+	if list == "" {
+		return entries, nil
+	}
+
+	for _, entry := range strings.Split(list, ",") {
+		kid, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || kid == "" || value == "" {
+			return nil, fmt.Errorf("%s must be a list of kid=value pairs", key)
+		}
+		if _, dup := entries[kid]; dup {
+			return nil, fmt.Errorf("%s: key ID %q is defined twice", key, kid)
+		}
+		entries[kid] = value
+	}
+	return entries, nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// Load builds the configuration from all sources and validates it. path is
+// an optional .yaml, .yml or .toml file.
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	file := map[string]interface{}{}
+	if path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &Config{}
+	if err := populate(reflect.ValueOf(cfg).Elem(), file, ""); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	return values, nil
+}
+
+// populate fills a config struct from the file values, the environment and
+// the defaults, in that order of lookup.
+func populate(v reflect.Value, file map[string]interface{}, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("config")
+
+		if field.Type.Kind() == reflect.Struct {
+			section, _ := file[field.Tag.Get("config")].(map[string]interface{})
+			if err := populate(v.Field(i), section, key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(field.Tag.Get("env"))
+		if !ok {
+			if value, inFile := file[field.Tag.Get("config")]; inFile {
+				raw, ok = fmt.Sprint(value), true
+			}
+		}
+		if !ok {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			continue
+		}
+
+		if err := setField(v.Field(i), raw); err != nil {
+			return fmt.Errorf("%s (%s): %w", key, field.Tag.Get("env"), err)
+		}
+	}
+	return nil
+}
+
+func setField(v reflect.Value, raw string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", func(key string, field reflect.StructField, v reflect.Value) {
+		if field.Tag.Get("required") == "true" && v.IsZero() {
+			errs = append(errs, fmt.Errorf("%s (%s) is required", key, field.Tag.Get("env")))
+		}
+	})
+
+	if c.FrontendURL != "" {
+		if u, err := url.Parse(c.FrontendURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("frontend_url: %q is not an http or https origin", c.FrontendURL))
+		}
+	}
+
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("database.sslmode: unknown mode %q", c.Database.SSLMode))
+	}
+
+	switch c.Broker.Kind {
+	case "memory", "postgres":
+	case "redis":
+		if c.Broker.RedisURL == "" {
+			errs = append(errs, errors.New("broker.redis_url (REDIS_URL) is required for the redis broker"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("broker.kind: unknown broker %q", c.Broker.Kind))
+	}
+
+	if err := c.Auth.validateKeys(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Auth.Leeway < 0 {
+		errs = append(errs, errors.New("auth.leeway must not be negative"))
+	}
+	if c.Auth.TokenTTL <= 0 || c.Auth.RefreshTTL <= 0 {
+		errs = append(errs, errors.New("auth.token_ttl and auth.refresh_ttl must be positive"))
+	}
+	if c.Hub.Shards < 1 || c.Hub.Workers < 1 || c.Hub.QueueSize < 1 {
+		errs = append(errs, errors.New("hub.shards, hub.workers and hub.queue_size must be positive"))
+	}
+	switch c.Hub.SlowConsumer {
+	case "disconnect", "drop-oldest":
+	default:
+		errs = append(errs, fmt.Errorf("hub.slow_consumer: unknown policy %q", c.Hub.SlowConsumer))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns the configuration as nested maps keyed like the config
+// file, with secrets masked, for --print-config.
+func (c *Config) Redacted() map[string]interface{} {
+	out := map[string]interface{}{}
+	walk(reflect.ValueOf(c).Elem(), "", func(key string, field reflect.StructField, v reflect.Value) {
+		section := out
+		parts := strings.Split(key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := section[part].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				section[part] = next
+			}
+			section = next
+		}
+
+		var value interface{} = v.Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if field.Tag.Get("secret") == "true" && !v.IsZero() {
+			value = "********"
+		}
+		section[parts[len(parts)-1]] = value
+	})
+	return out
+}
+
+// Print writes the redacted configuration as YAML.
+func (c *Config) Print() error {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func walk(v reflect.Value, prefix string, fn func(key string, field reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("config")
+		if field.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), key+".", fn)
+			continue
+		}
+		fn(key, field, v.Field(i))
+	}
+}
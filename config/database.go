@@ -1,11 +1,8 @@
 package config
 
 import (
-	"fmt"
 	"log"
-	"os"
 
-	"github.com/prajapatiomkar/wave-server/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,18 +10,9 @@ import (
 
 var DB *gorm.DB
 
-func ConnectDatabase() {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=require",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
-
+func ConnectDatabase(cfg DatabaseConfig) {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
+	DB, err = gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
 
@@ -33,13 +21,6 @@ func ConnectDatabase() {
 	}
 
 	log.Println("✅ Database connected successfully")
-
-	// Auto migrate models
-	if err := DB.AutoMigrate(&models.User{}, &models.Message{}); err != nil {
-		log.Fatal("Failed to migrate database:", err)
-	}
-
-	log.Println("✅ Database migration completed")
 }
 
 func GetDB() *gorm.DB {
@@ -0,0 +1,10 @@
+package auth
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims are the claims of an access token.
+type Claims struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
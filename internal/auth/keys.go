@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prajapatiomkar/wave-server/config"
+)
+
+// key is one entry of the key set. The algorithm follows from the key type:
+// HMAC secrets sign HS256, RSA keys RS256 and Ed25519 keys EdDSA. private is
+// nil for keys that only verify.
+type key struct {
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+type keySet struct {
+	keys       map[string]*key
+	signingKID string
+}
+
+func newKeySet(cfg config.AuthConfig) (*keySet, error) {
+	set := &keySet{keys: make(map[string]*key), signingKID: cfg.JWTKeyID}
+
+	hmacKeys, err := cfg.HMACKeys()
+	if err != nil {
+		return nil, err
+	}
+	for kid, secret := range hmacKeys {
+		set.keys[kid] = &key{method: jwt.SigningMethodHS256, private: secret, public: secret}
+	}
+
+	privateFiles, err := cfg.PrivateKeyFiles()
+	if err != nil {
+		return nil, err
+	}
+	for kid, path := range privateFiles {
+		if set.keys[kid], err = loadPrivateKey(path); err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+	}
+
+	publicFiles, err := cfg.PublicKeyFiles()
+	if err != nil {
+		return nil, err
+	}
+	for kid, path := range publicFiles {
+		if set.keys[kid], err = loadPublicKey(path); err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+	}
+
+	if signing, ok := set.keys[set.signingKID]; !ok || signing.private == nil {
+		return nil, fmt.Errorf("no signing key with ID %q", set.signingKID)
+	}
+	return set, nil
+}
+
+func (s *keySet) signing() *key {
+	return s.keys[s.signingKID]
+}
+
+func (s *keySet) methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, k := range s.keys {
+		if alg := k.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func loadPrivateKey(path string) (*key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var private crypto.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		return &key{method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &key{method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T; use RSA or Ed25519", private)
+	}
+}
+
+func loadPublicKey(path string) (*key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var public crypto.PublicKey
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		return &key{method: jwt.SigningMethodRS256, public: public}, nil
+	case ed25519.PublicKey:
+		return &key{method: jwt.SigningMethodEdDSA, public: public}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T; use RSA or Ed25519", public)
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwks publishes the asymmetric public keys. HMAC secrets are never
+// published, so tokens signed with them can only be verified here.
+func (s *keySet) jwks() *JWKS {
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := &JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		jwk := JWK{KeyID: kid, Use: "sig", Algorithm: s.keys[kid].method.Alg()}
+		switch public := s.keys[kid].public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prajapatiomkar/wave-server/config"
+)
+
+// WebSocketProtocol is the Sec-WebSocket-Protocol a browser offers together
+// with its token, since browsers cannot set an Authorization header on a
+// WebSocket handshake: `new WebSocket(url, ["bearer", token])`. The server
+// selects it so the token itself is never echoed back.
+const WebSocketProtocol = "bearer"
+
+// defaultKeyID verifies tokens issued before key IDs were introduced.
+const defaultKeyID = "default"
+
+var (
+	ErrMissingToken = errors.New("token required")
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrRevokedToken = errors.New("token has been revoked")
+)
+
+// Revocations reports whether a session or token ID has been revoked.
+type Revocations interface {
+	IsRevoked(id string) bool
+}
+
+// TokenSigner issues access tokens.
+type TokenSigner struct {
+	keys     *keySet
+	issuer   string
+	audience string
+}
+
+func NewTokenSigner(cfg config.AuthConfig) (*TokenSigner, error) {
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenSigner{keys: keys, issuer: cfg.Issuer, audience: cfg.Audience}, nil
+}
+
+func (s *TokenSigner) Sign(userID uint, sessionID string, expiresAt time.Time) (string, error) {
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
+
+	signing := s.keys.signing()
+	now := time.Now()
+	token := jwt.NewWithClaims(signing.method, &Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        base64.RawURLEncoding.EncodeToString(tokenID),
+			Issuer:    s.issuer,
+			Audience:  jwt.ClaimStrings{s.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	token.Header["kid"] = s.keys.signingKID
+	return token.SignedString(signing.private)
+}
+
+// TokenVerifier checks access tokens for both REST requests and WebSocket
+// handshakes.
+type TokenVerifier struct {
+	keys        *keySet
+	parser      *jwt.Parser
+	revocations Revocations
+}
+
+func NewTokenVerifier(cfg config.AuthConfig, revocations Revocations) (*TokenVerifier, error) {
+	keys, err := newKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenVerifier{
+		keys: keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(keys.methods()),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithLeeway(cfg.Leeway),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+		revocations: revocations,
+	}, nil
+}
+
+// Verify validates a token's signature and claims and that neither it nor
+// its session has been revoked.
+func (v *TokenVerifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.key); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.UserID == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	if v.revocations.IsRevoked(claims.SessionID) || v.revocations.IsRevoked(claims.ID) {
+		return nil, ErrRevokedToken
+	}
+
+	return claims, nil
+}
+
+// VerifyRequest verifies the token a request carries, see TokenFromRequest.
+func (v *TokenVerifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	token := TokenFromRequest(r)
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	return v.Verify(token)
+}
+
+func (v *TokenVerifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = defaultKeyID
+	}
+
+	key, ok := v.keys.keys[kid]
+	// Each key only verifies its own algorithm, so an RSA public key can
+	// never be mistaken for an HMAC secret.
+	if !ok || token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys other services can verify tokens with.
+func (v *TokenVerifier) JWKS() *JWKS {
+	return v.keys.jwks()
+}
+
+// TokenFromRequest reads a bearer token from the Authorization header or,
+// for browser WebSocket handshakes, from the Sec-WebSocket-Protocol list
+// following WebSocketProtocol.
+func TokenFromRequest(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	protocols := websocketProtocols(r)
+	for i, protocol := range protocols {
+		if protocol == WebSocketProtocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
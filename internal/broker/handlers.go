@@ -0,0 +1,35 @@
+package broker
+
+import "sync"
+
+// handlerSet maps rooms to the deliver callbacks registered by the hub.
+type handlerSet struct {
+	mu       sync.RWMutex
+	handlers map[string]func(payload []byte)
+}
+
+func newHandlerSet() *handlerSet {
+	return &handlerSet{handlers: make(map[string]func(payload []byte))}
+}
+
+func (s *handlerSet) set(roomID string, deliver func(payload []byte)) {
+	s.mu.Lock()
+	s.handlers[roomID] = deliver
+	s.mu.Unlock()
+}
+
+func (s *handlerSet) remove(roomID string) {
+	s.mu.Lock()
+	delete(s.handlers, roomID)
+	s.mu.Unlock()
+}
+
+func (s *handlerSet) deliver(roomID string, payload []byte) {
+	s.mu.RLock()
+	deliver := s.handlers[roomID]
+	s.mu.RUnlock()
+
+	if deliver != nil {
+		deliver(payload)
+	}
+}
@@ -0,0 +1,30 @@
+package broker
+
+// MemoryBroker delivers events within the process. It is the default for
+// single-node deployments.
+type MemoryBroker struct {
+	handlers *handlerSet
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{handlers: newHandlerSet()}
+}
+
+func (b *MemoryBroker) Publish(roomID string, payload []byte) error {
+	b.handlers.deliver(roomID, payload)
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(roomID string, deliver func(payload []byte)) error {
+	b.handlers.set(roomID, deliver)
+	return nil
+}
+
+func (b *MemoryBroker) Unsubscribe(roomID string) error {
+	b.handlers.remove(roomID)
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,150 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	postgresChannel = "wave_hub"
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more.
+	maxNotifyPayload = 7900
+	reconnectDelay   = 2 * time.Second
+)
+
+type envelope struct {
+	RoomID  string          `json:"room_id"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// PostgresBroker relays hub events between nodes with LISTEN/NOTIFY. Events
+// are published through the shared gorm pool and received on a dedicated
+// connection, which is re-established if it drops. All rooms share one
+// channel; events for rooms without local subscribers are dropped on arrival.
+type PostgresBroker struct {
+	db       *gorm.DB
+	dsn      string
+	handlers *handlerSet
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPostgresBroker(db *gorm.DB, dsn string) (*PostgresBroker, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &PostgresBroker{
+		db:       db,
+		dsn:      dsn,
+		handlers: newHandlerSet(),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	conn, err := b.listen()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.receive(conn)
+	}()
+
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(roomID string, payload []byte) error {
+	data, err := json.Marshal(envelope{RoomID: roomID, Payload: payload})
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxNotifyPayload {
+		return errors.New("broker: event too large for NOTIFY")
+	}
+
+	return b.db.Exec("SELECT pg_notify(?, ?)", postgresChannel, string(data)).Error
+}
+
+func (b *PostgresBroker) Subscribe(roomID string, deliver func(payload []byte)) error {
+	b.handlers.set(roomID, deliver)
+	return nil
+}
+
+func (b *PostgresBroker) Unsubscribe(roomID string) error {
+	b.handlers.remove(roomID)
+	return nil
+}
+
+func (b *PostgresBroker) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
+
+func (b *PostgresBroker) listen() (*pgx.Conn, error) {
+	conn, err := pgx.Connect(b.ctx, b.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(b.ctx, "LISTEN "+postgresChannel); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (b *PostgresBroker) receive(conn *pgx.Conn) {
+	for {
+		notification, err := conn.WaitForNotification(b.ctx)
+		if err != nil {
+			conn.Close(context.Background())
+			if b.ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("❌ Broker connection lost: %v", err)
+			if conn = b.reconnect(); conn == nil {
+				return
+			}
+			continue
+		}
+
+		var env envelope
+		if err := json.Unmarshal([]byte(notification.Payload), &env); err != nil {
+			log.Printf("Error parsing broker event: %v", err)
+			continue
+		}
+
+		b.handlers.deliver(env.RoomID, env.Payload)
+	}
+}
+
+func (b *PostgresBroker) reconnect() *pgx.Conn {
+	for {
+		select {
+		case <-b.ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+
+		conn, err := b.listen()
+		if err == nil {
+			log.Println("✅ Broker reconnected")
+			return conn
+		}
+		log.Printf("❌ Broker reconnect failed: %v", err)
+	}
+}
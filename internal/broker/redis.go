@@ -0,0 +1,93 @@
+package broker
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisChannelPrefix = "wave:room:"
+
+// RedisBroker relays events through Redis pub/sub with one channel per room,
+// so each node only receives traffic for rooms it has local subscribers in.
+type RedisBroker struct {
+	client   *redis.Client
+	pubsub   *redis.PubSub
+	handlers *handlerSet
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewRedisBroker(url string) (*RedisBroker, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		cancel()
+		client.Close()
+		return nil, err
+	}
+
+	b := &RedisBroker{
+		client:   client,
+		pubsub:   client.Subscribe(ctx),
+		handlers: newHandlerSet(),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go b.receive()
+
+	return b, nil
+}
+
+func (b *RedisBroker) Publish(roomID string, payload []byte) error {
+	return b.client.Publish(b.ctx, redisChannelPrefix+roomID, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(roomID string, deliver func(payload []byte)) error {
+	b.handlers.set(roomID, deliver)
+	if err := b.pubsub.Subscribe(b.ctx, redisChannelPrefix+roomID); err != nil {
+		b.handlers.remove(roomID)
+		return err
+	}
+	return nil
+}
+
+func (b *RedisBroker) Unsubscribe(roomID string) error {
+	b.handlers.remove(roomID)
+	return b.pubsub.Unsubscribe(b.ctx, redisChannelPrefix+roomID)
+}
+
+func (b *RedisBroker) Close() error {
+	b.cancel()
+	err := b.pubsub.Close()
+	<-b.done
+	if closeErr := b.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// receive runs until the pub/sub connection is closed. go-redis reconnects
+// and resubscribes on its own if the connection drops.
+func (b *RedisBroker) receive() {
+	defer close(b.done)
+
+	for msg := range b.pubsub.Channel() {
+		roomID, ok := strings.CutPrefix(msg.Channel, redisChannelPrefix)
+		if !ok {
+			log.Printf("Ignoring event on unexpected channel %s", msg.Channel)
+			continue
+		}
+		b.handlers.deliver(roomID, []byte(msg.Payload))
+	}
+}
@@ -0,0 +1,78 @@
+package broker
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestRedisBroker connects to the redis-server at REDIS_URL, e.g.
+// redis://localhost:6379/15, and skips the test when it is not set.
+func newTestRedisBroker(t *testing.T) *RedisBroker {
+	t.Helper()
+
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	b, err := NewRedisBroker(url)
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", url, err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestRedisBrokerPublishSubscribe(t *testing.T) {
+	publisher := newTestRedisBroker(t)
+	subscriber := newTestRedisBroker(t)
+
+	roomID := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	received := make(chan string, 16)
+	if err := subscriber.Subscribe(roomID, func(payload []byte) {
+		received <- string(payload)
+	}); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// Redis confirms subscriptions asynchronously, so publish until the
+	// subscriber is listening.
+	deadline := time.After(5 * time.Second)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for got := false; !got; {
+		if err := publisher.Publish(roomID, []byte("hello")); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		select {
+		case payload := <-received:
+			if payload != "hello" {
+				t.Fatalf("received %q, want %q", payload, "hello")
+			}
+			got = true
+		case <-ticker.C:
+		case <-deadline:
+			t.Fatal("subscriber never received the event")
+		}
+	}
+
+	if err := subscriber.Unsubscribe(roomID); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	// Drain retries still in flight from before the unsubscribe.
+	time.Sleep(100 * time.Millisecond)
+	for len(received) > 0 {
+		<-received
+	}
+
+	if err := publisher.Publish(roomID, []byte("after unsubscribe")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	select {
+	case payload := <-received:
+		t.Fatalf("received %q after unsubscribing", payload)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
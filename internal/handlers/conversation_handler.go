@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prajapatiomkar/wave-server/internal/services"
+)
+
+type ConversationHandler struct {
+	conversationService *services.ConversationService
+}
+
+func NewConversationHandler(conversationService *services.ConversationService) *ConversationHandler {
+	return &ConversationHandler{conversationService: conversationService}
+}
+
+func (h *ConversationHandler) ListConversations(c *gin.Context) {
+	limit := 20
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	conversations, nextCursor, err := h.conversationService.ListConversations(c.GetUint("user_id"), c.Query("cursor"), limit)
+	if err != nil {
+		if err == services.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations, "next_cursor": nextCursor})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prajapatiomkar/wave-server/internal/services"
+)
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotFound),
+		errors.Is(err, services.ErrMessageNotFound), errors.Is(err, services.ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrNotMember):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrSlugTaken), errors.Is(err, services.ErrRoomArchived),
+		errors.Is(err, services.ErrOwnerLeave):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidSlug), errors.Is(err, services.ErrSelfDirect),
+		errors.Is(err, services.ErrEmptyContent), errors.Is(err, services.ErrContentTooLong),
+		errors.Is(err, services.ErrInvalidEmoji),
+		errors.Is(err, services.ErrInvalidCursor), errors.Is(err, services.ErrInvalidClientMsgID):
+		return http.StatusBadRequest
+	default:
+		// Anything unrecognised is a storage failure, not the client's fault.
+		return http.StatusInternalServerError
+	}
+}
@@ -1,23 +1,26 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prajapatiomkar/wave-server/internal/services"
+	ws "github.com/prajapatiomkar/wave-server/internal/websocket"
 )
 
 type MessageHandler struct {
 	messageService *services.MessageService
+	hub            *ws.Hub
 }
 
-func NewMessageHandler(messageService *services.MessageService) *MessageHandler {
-	return &MessageHandler{messageService: messageService}
+func NewMessageHandler(messageService *services.MessageService, hub *ws.Hub) *MessageHandler {
+	return &MessageHandler{messageService: messageService, hub: hub}
 }
 
 func (h *MessageHandler) GetMessageHistory(c *gin.Context) {
-	roomID := c.Param("room_id")
+	roomID := c.Param("id")
 	if roomID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "room_id required"})
 		return
@@ -38,11 +41,119 @@ func (h *MessageHandler) GetMessageHistory(c *gin.Context) {
 		}
 	}
 
-	messages, err := h.messageService.GetMessageHistory(roomID, limit, offset)
+	messages, err := h.messageService.GetMessageHistory(roomID, c.GetUint("user_id"), limit, offset)
 	if err != nil {
+		if errors.Is(err, services.ErrRoomNotFound) || errors.Is(err, services.ErrNotMember) {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"messages": messages})
 }
+
+func (h *MessageHandler) EditMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	var req services.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message, err := h.messageService.EditMessage(uint(messageID), c.GetUint("user_id"), req.Content)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastEvent(message)
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (h *MessageHandler) DeleteMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	message, err := h.messageService.DeleteMessage(uint(messageID), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.BroadcastEvent(message)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+}
+
+func (h *MessageHandler) GetThread(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	thread, err := h.messageService.GetThread(uint(messageID), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"thread": thread})
+}
+
+func (h *MessageHandler) AddReaction(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	var req services.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.messageService.AddReaction(uint(messageID), c.GetUint("user_id"), req.Emoji)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if event != nil {
+		h.hub.BroadcastEvent(event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction added"})
+}
+
+func (h *MessageHandler) RemoveReaction(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	event, err := h.messageService.RemoveReaction(uint(messageID), c.GetUint("user_id"), c.Param("emoji"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if event != nil {
+		h.hub.BroadcastEvent(event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction removed"})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prajapatiomkar/wave-server/internal/services"
+)
+
+const maxPresenceLookup = 200
+
+type PresenceHandler struct {
+	presenceService *services.PresenceService
+}
+
+func NewPresenceHandler(presenceService *services.PresenceService) *PresenceHandler {
+	return &PresenceHandler{presenceService: presenceService}
+}
+
+func (h *PresenceHandler) GetPresence(c *gin.Context) {
+	var userIDs []uint
+	for _, part := range strings.Split(c.Query("user_ids"), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_ids"})
+			return
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+
+	if len(userIDs) == 0 || len(userIDs) > maxPresenceLookup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_ids must list between 1 and 200 ids"})
+		return
+	}
+
+	presence, err := h.presenceService.GetPresence(userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"presence": presence})
+}
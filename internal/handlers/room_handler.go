@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prajapatiomkar/wave-server/internal/services"
+	ws "github.com/prajapatiomkar/wave-server/internal/websocket"
+)
+
+type RoomHandler struct {
+	roomService    *services.RoomService
+	messageService *services.MessageService
+	hub            *ws.Hub
+}
+
+func NewRoomHandler(roomService *services.RoomService, messageService *services.MessageService, hub *ws.Hub) *RoomHandler {
+	return &RoomHandler{roomService: roomService, messageService: messageService, hub: hub}
+}
+
+func (h *RoomHandler) CreateRoom(c *gin.Context) {
+	var req services.CreateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, err := h.roomService.CreateRoom(c.GetUint("user_id"), &req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"room": room})
+}
+
+func (h *RoomHandler) ListRooms(c *gin.Context) {
+	limit := 50
+	offset := 0
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil {
+			offset = o
+		}
+	}
+
+	rooms, err := h.roomService.ListRooms(c.GetUint("user_id"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rooms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rooms": rooms})
+}
+
+func (h *RoomHandler) GetRoom(c *gin.Context) {
+	room, err := h.roomService.GetRoom(c.Param("room_id"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"room": room})
+}
+
+func (h *RoomHandler) UpdateRoom(c *gin.Context) {
+	var req services.UpdateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, err := h.roomService.UpdateRoom(c.Param("room_id"), c.GetUint("user_id"), &req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"room": room})
+}
+
+func (h *RoomHandler) ArchiveRoom(c *gin.Context) {
+	room, err := h.roomService.ArchiveRoom(c.Param("room_id"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"room": room})
+}
+
+func (h *RoomHandler) JoinRoom(c *gin.Context) {
+	member, err := h.roomService.JoinRoom(c.Param("room_id"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+func (h *RoomHandler) LeaveRoom(c *gin.Context) {
+	roomID := c.Param("room_id")
+	userID := c.GetUint("user_id")
+
+	if err := h.roomService.LeaveRoom(roomID, userID); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.RemoveUser(roomID, userID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left room"})
+}
+
+func (h *RoomHandler) ListMembers(c *gin.Context) {
+	members, err := h.roomService.ListMembers(c.Param("room_id"), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+func (h *RoomHandler) InviteMember(c *gin.Context) {
+	var req services.InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.roomService.InviteMember(c.Param("room_id"), c.GetUint("user_id"), &req)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+func (h *RoomHandler) KickMember(c *gin.Context) {
+	roomID := c.Param("room_id")
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	if err := h.roomService.KickMember(roomID, c.GetUint("user_id"), uint(userID)); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.hub.RemoveUser(roomID, uint(userID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func (h *RoomHandler) OpenDirectMessage(c *gin.Context) {
+	otherID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	conversation, err := h.roomService.OpenDirectRoom(c.GetUint("user_id"), uint(otherID))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversation": conversation})
+}
+
+func (h *RoomHandler) ListDirectMessages(c *gin.Context) {
+	conversations, err := h.roomService.ListDirectRooms(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+func (h *RoomHandler) MarkRead(c *gin.Context) {
+	var req services.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	receipt, err := h.messageService.MarkRead(c.Param("room_id"), c.GetUint("user_id"), req.MessageID)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if receipt != nil {
+		h.hub.BroadcastReceipt(receipt)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Read cursor updated"})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prajapatiomkar/wave-server/internal/services"
+	ws "github.com/prajapatiomkar/wave-server/internal/websocket"
+)
+
+type SessionHandler struct {
+	authService *services.AuthService
+	hub         *ws.Hub
+}
+
+func NewSessionHandler(authService *services.AuthService, hub *ws.Hub) *SessionHandler {
+	return &SessionHandler{authService: authService, hub: hub}
+}
+
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	sessions, err := h.authService.ListSessions(userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	// Counts cover the whole cluster, as announced over the presence room.
+	connections := h.hub.SessionConnections(userID)
+	for i := range sessions {
+		sessions[i].Connections = connections[sessions[i].ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	if err := h.authService.RevokeSession(c.GetUint("user_id"), c.Param("id")); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
@@ -3,64 +3,63 @@ package handlers
 import (
 	"log"
 	"net/http"
-	"os"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
+	"github.com/prajapatiomkar/wave-server/internal/auth"
+	"github.com/prajapatiomkar/wave-server/internal/services"
 	ws "github.com/prajapatiomkar/wave-server/internal/websocket"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    []string{auth.WebSocketProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
 type WebSocketHandler struct {
-	hub *ws.Hub
+	hub         *ws.Hub
+	roomService *services.RoomService
+	verifier    *auth.TokenVerifier
 }
 
-func NewWebSocketHandler(hub *ws.Hub) *WebSocketHandler {
-	return &WebSocketHandler{hub: hub}
+func NewWebSocketHandler(hub *ws.Hub, roomService *services.RoomService, verifier *auth.TokenVerifier) *WebSocketHandler {
+	return &WebSocketHandler{hub: hub, roomService: roomService, verifier: verifier}
 }
 
 func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
-	roomID := c.Query("room_id")
-	if roomID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "room_id required"})
-		return
-	}
-
-	token := c.Query("token")
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+	// The token comes in the Authorization header or, from browsers, in
+	// Sec-WebSocket-Protocol; never in the query string, which ends up in
+	// access logs.
+	claims, err := h.verifier.VerifyRequest(c.Request)
+	if err != nil {
+		log.Printf("Token validation error: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
+	userID := claims.UserID
 
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
+	// room_id is optional; it subscribes the connection to a first room so
+	// single-room clients keep working without sending a subscribe frame.
+	// since_seq resumes that room after a reconnect.
+	roomID := c.Query("room_id")
+	var sinceSeq int64
+	if sinceStr := c.Query("since_seq"); sinceStr != "" {
+		if s, err := strconv.ParseInt(sinceStr, 10, 64); err == nil {
+			sinceSeq = s
 		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil || !parsedToken.Valid {
-		log.Printf("Token validation error: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
 	}
-
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-		return
+	if roomID != "" {
+		if _, _, err := h.roomService.RequireMember(roomID, userID); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	userID := uint(claims["user_id"].(float64))
 	username := c.Query("username")
 	if username == "" {
 		username = "User" + strconv.Itoa(int(userID))
@@ -72,18 +71,15 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		return
 	}
 
-	client := &ws.Client{
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
-		UserID:   userID,
-		Username: username,
-		RoomID:   roomID,
-	}
+	client := ws.NewClient(conn, userID, username, claims.SessionID)
 
 	h.hub.Register <- client
+	if roomID != "" {
+		h.hub.Subscribe <- &ws.Subscription{Client: client, RoomID: roomID, SinceSeq: sinceSeq}
+	}
 
 	go client.WritePump()
 	go client.ReadPump()
 
-	log.Printf("✅ WebSocket connected: user=%s room=%s", username, roomID)
+	log.Printf("✅ WebSocket connected: user=%s", username)
 }
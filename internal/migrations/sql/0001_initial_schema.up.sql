@@ -0,0 +1,118 @@
+-- Baseline matching the schema previously created by AutoMigrate. Every
+-- statement is guarded so databases created that way can adopt it as is:
+-- tables that already exist are skipped by CREATE TABLE IF NOT EXISTS, so
+-- columns added to them since are brought in by ADD COLUMN IF NOT EXISTS.
+
+CREATE TABLE IF NOT EXISTS users (
+    id          bigserial PRIMARY KEY,
+    username    varchar(50)  NOT NULL,
+    email       varchar(100) NOT NULL,
+    password    text         NOT NULL,
+    full_name   varchar(100),
+    avatar      text,
+    is_online   boolean      DEFAULT false,
+    status      varchar(20)  NOT NULL DEFAULT 'offline',
+    last_seen   timestamptz,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'offline';
+UPDATE users SET status = 'online' WHERE is_online AND status = 'offline';
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id           bigserial PRIMARY KEY,
+    name         varchar(100) NOT NULL,
+    slug         varchar(100) NOT NULL,
+    topic        varchar(255),
+    owner_id     bigint       NOT NULL,
+    visibility   varchar(20)  NOT NULL DEFAULT 'public',
+    kind         varchar(20)  NOT NULL DEFAULT 'channel',
+    archived_at  timestamptz,
+    last_seq     bigint       NOT NULL DEFAULT 0,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    deleted_at   timestamptz,
+    CONSTRAINT uni_rooms_slug UNIQUE (slug),
+    CONSTRAINT fk_rooms_owner FOREIGN KEY (owner_id) REFERENCES users (id)
+);
+ALTER TABLE rooms
+    ADD COLUMN IF NOT EXISTS kind     varchar(20) NOT NULL DEFAULT 'channel',
+    ADD COLUMN IF NOT EXISTS last_seq bigint      NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_rooms_owner_id ON rooms (owner_id);
+CREATE INDEX IF NOT EXISTS idx_rooms_kind ON rooms (kind);
+CREATE INDEX IF NOT EXISTS idx_rooms_deleted_at ON rooms (deleted_at);
+
+CREATE TABLE IF NOT EXISTS room_members (
+    id                    bigserial PRIMARY KEY,
+    room_id               bigint      NOT NULL,
+    user_id               bigint      NOT NULL,
+    role                  varchar(20) NOT NULL DEFAULT 'member',
+    created_at            timestamptz,
+    updated_at            timestamptz,
+    last_read_message_id  bigint      NOT NULL DEFAULT 0,
+    last_read_at          timestamptz,
+    CONSTRAINT fk_room_members_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+ALTER TABLE room_members
+    ADD COLUMN IF NOT EXISTS last_read_message_id bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_read_at         timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_room_members_room_user ON room_members (room_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_room_members_user_id ON room_members (user_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id              bigserial PRIMARY KEY,
+    room_id         text        NOT NULL,
+    seq             bigint      NOT NULL DEFAULT 0,
+    user_id         bigint      NOT NULL,
+    client_msg_id   varchar(64) NOT NULL DEFAULT '',
+    content         text        NOT NULL,
+    type            text        DEFAULT 'text',
+    edited_at       timestamptz,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    deleted_by      bigint,
+    parent_id       bigint,
+    thread_root_id  bigint,
+    reply_count     bigint      NOT NULL DEFAULT 0,
+    last_reply_at   timestamptz,
+    CONSTRAINT fk_messages_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS seq            bigint      NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS client_msg_id  varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS edited_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_by     bigint,
+    ADD COLUMN IF NOT EXISTS parent_id      bigint,
+    ADD COLUMN IF NOT EXISTS thread_root_id bigint,
+    ADD COLUMN IF NOT EXISTS reply_count    bigint      NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_reply_at  timestamptz;
+CREATE INDEX IF NOT EXISTS idx_messages_room_id ON messages (room_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_room_seq ON messages (room_id, seq) WHERE seq > 0;
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_user_client_msg ON messages (user_id, client_msg_id) WHERE client_msg_id <> '';
+CREATE INDEX IF NOT EXISTS idx_messages_deleted_at ON messages (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_messages_parent_id ON messages (parent_id);
+CREATE INDEX IF NOT EXISTS idx_messages_thread_root_id ON messages (thread_root_id);
+
+CREATE TABLE IF NOT EXISTS message_edits (
+    id          bigserial PRIMARY KEY,
+    message_id  bigint NOT NULL,
+    user_id     bigint NOT NULL,
+    content     text   NOT NULL,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits (message_id);
+
+CREATE TABLE IF NOT EXISTS message_reactions (
+    id          bigserial PRIMARY KEY,
+    message_id  bigint      NOT NULL,
+    user_id     bigint      NOT NULL,
+    emoji       varchar(64) NOT NULL,
+    created_at  timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_reactions_message_user_emoji ON message_reactions (message_id, user_id, emoji);
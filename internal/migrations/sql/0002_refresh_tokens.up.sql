@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens (
+    id          bigserial PRIMARY KEY,
+    user_id     bigint      NOT NULL REFERENCES users (id),
+    family_id   varchar(64) NOT NULL,
+    token_hash  varchar(64) NOT NULL,
+    expires_at  timestamptz NOT NULL,
+    rotated_at  timestamptz,
+    revoked_at  timestamptz,
+    created_at  timestamptz
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
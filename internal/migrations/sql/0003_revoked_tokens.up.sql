@@ -0,0 +1,9 @@
+CREATE TABLE revoked_tokens (
+    id          varchar(64) PRIMARY KEY,
+    user_id     bigint      NOT NULL,
+    expires_at  timestamptz NOT NULL,
+    created_at  timestamptz
+);
+CREATE INDEX idx_revoked_tokens_user_id ON revoked_tokens (user_id);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+CREATE INDEX idx_revoked_tokens_created_at ON revoked_tokens (created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE sessions (
+    id            varchar(64)  PRIMARY KEY,
+    user_id       bigint       NOT NULL REFERENCES users (id),
+    user_agent    varchar(255),
+    ip            varchar(64),
+    created_at    timestamptz,
+    last_used_at  timestamptz,
+    revoked_at    timestamptz
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
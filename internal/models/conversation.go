@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type MessagePreview struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ConversationResponse struct {
+	Room           RoomResponse    `json:"room"`
+	LastMessage    *MessagePreview `json:"last_message"`
+	UnreadCount    int             `json:"unread_count"`
+	MentionCount   int             `json:"mention_count"`
+	LastActivityAt time.Time       `json:"last_activity_at"`
+}
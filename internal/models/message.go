@@ -7,24 +7,47 @@ import (
 )
 
 type Message struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	RoomID    string         `gorm:"index;not null" json:"room_id"`
-	UserID    uint           `gorm:"index;not null" json:"user_id"`
-	Content   string         `gorm:"type:text;not null" json:"content"`
-	Type      string         `gorm:"default:'text'" json:"type"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	RoomID      string         `gorm:"index;not null;uniqueIndex:idx_messages_room_seq,where:seq > 0" json:"room_id"`
+	Seq         int64          `gorm:"not null;default:0;uniqueIndex:idx_messages_room_seq,where:seq > 0" json:"seq"`
+	UserID      uint           `gorm:"index;not null;uniqueIndex:idx_messages_user_client_msg,where:client_msg_id <> ''" json:"user_id"`
+	ClientMsgID string         `gorm:"size:64;not null;default:'';uniqueIndex:idx_messages_user_client_msg,where:client_msg_id <> ''" json:"client_msg_id,omitempty"`
+	Content     string         `gorm:"type:text;not null" json:"content"`
+	Type        string         `gorm:"default:'text'" json:"type"`
+	EditedAt    *time.Time     `json:"edited_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	DeletedBy   *uint          `json:"-"`
+
+	ParentID     *uint      `gorm:"index" json:"parent_id"`
+	ThreadRootID *uint      `gorm:"index" json:"thread_root_id"`
+	ReplyCount   int        `gorm:"not null;default:0" json:"reply_count"`
+	LastReplyAt  *time.Time `json:"last_reply_at"`
 
 	User User `gorm:"foreignKey:UserID" json:"user"`
 }
 
 type MessageResponse struct {
-	ID        uint         `json:"id"`
-	RoomID    string       `json:"room_id"`
-	UserID    uint         `json:"user_id"`
-	Content   string       `json:"content"`
-	Type      string       `json:"type"`
-	CreatedAt time.Time    `json:"created_at"`
-	User      UserResponse `json:"user"`
+	ID           uint              `json:"id"`
+	RoomID       string            `json:"room_id"`
+	Seq          int64             `json:"seq"`
+	ClientMsgID  string            `json:"client_msg_id,omitempty"`
+	UserID       uint              `json:"user_id"`
+	Content      string            `json:"content"`
+	Type         string            `json:"type"`
+	ParentID     *uint             `json:"parent_id,omitempty"`
+	ThreadRootID *uint             `json:"thread_root_id,omitempty"`
+	ReplyCount   int               `json:"reply_count"`
+	LastReplyAt  *time.Time        `json:"last_reply_at,omitempty"`
+	EditedAt     *time.Time        `json:"edited_at"`
+	DeletedAt    *time.Time        `json:"deleted_at,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	User         UserResponse      `json:"user"`
+	Reactions    []ReactionSummary `json:"reactions"`
+}
+
+type ThreadResponse struct {
+	Root    MessageResponse   `json:"root"`
+	Replies []MessageResponse `json:"replies"`
 }
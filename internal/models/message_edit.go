@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// MessageEdit records the content a message had before an edit.
+type MessageEdit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MessageID uint      `gorm:"index;not null" json:"message_id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	Content   string    `gorm:"type:text;not null" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type MessageReaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MessageID uint      `gorm:"not null;uniqueIndex:idx_message_reactions_message_user_emoji" json:"message_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_message_reactions_message_user_emoji" json:"user_id"`
+	Emoji     string    `gorm:"not null;size:64;uniqueIndex:idx_message_reactions_message_user_emoji" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
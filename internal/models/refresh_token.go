@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken stores the SHA-256 of an opaque refresh token. Tokens issued
+// by rotating one another share a FamilyID, so reuse of a rotated token can
+// revoke every token descended from the same login.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID  string     `gorm:"index;not null;size:64" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RevokedToken blocks a session ID or a single token jti until ExpiresAt,
+// after which no access token carrying it can still be valid.
+type RevokedToken struct {
+	ID        string    `gorm:"primaryKey;size:64" json:"id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
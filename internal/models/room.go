@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoomVisibilityPublic  = "public"
+	RoomVisibilityPrivate = "private"
+
+	RoomKindChannel = "channel"
+	RoomKindDirect  = "direct"
+)
+
+type Room struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Name       string         `gorm:"not null;size:100" json:"name"`
+	Slug       string         `gorm:"unique;not null;size:100" json:"slug"`
+	Topic      string         `gorm:"size:255" json:"topic"`
+	OwnerID    uint           `gorm:"index;not null" json:"owner_id"`
+	Visibility string         `gorm:"not null;size:20;default:'public'" json:"visibility"`
+	Kind       string         `gorm:"not null;size:20;default:'channel';index" json:"kind"`
+	ArchivedAt *time.Time     `json:"archived_at"`
+	LastSeq    int64          `gorm:"not null;default:0" json:"last_seq"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+
+	Owner User `gorm:"foreignKey:OwnerID" json:"owner"`
+}
+
+type RoomResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Slug       string     `json:"slug"`
+	Topic      string     `json:"topic"`
+	OwnerID    uint       `json:"owner_id"`
+	Visibility string     `json:"visibility"`
+	Kind       string     `json:"kind"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type DirectConversationResponse struct {
+	Room        RoomResponse `json:"room"`
+	Participant UserResponse `json:"participant"`
+}
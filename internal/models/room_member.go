@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	RoomRoleOwner  = "owner"
+	RoomRoleAdmin  = "admin"
+	RoomRoleMember = "member"
+	RoomRoleGuest  = "guest"
+)
+
+type RoomMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	RoomID    uint      `gorm:"not null;uniqueIndex:idx_room_members_room_user" json:"room_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_room_members_room_user;index" json:"user_id"`
+	Role      string    `gorm:"not null;size:20;default:'member'" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LastReadMessageID uint       `gorm:"not null;default:0" json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
+
+func (m *RoomMember) CanModerate() bool {
+	return m.Role == RoomRoleOwner || m.Role == RoomRoleAdmin
+}
+
+func (m *RoomMember) CanPost() bool {
+	return m.Role != RoomRoleGuest
+}
+
+type RoomMemberResponse struct {
+	User              UserResponse `json:"user"`
+	Role              string       `json:"role"`
+	JoinedAt          time.Time    `json:"joined_at"`
+	LastReadMessageID uint         `json:"last_read_message_id"`
+	LastReadAt        *time.Time   `json:"last_read_at"`
+}
@@ -6,6 +6,12 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	UserStatusOnline  = "online"
+	UserStatusAway    = "away"
+	UserStatusOffline = "offline"
+)
+
 type User struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
 	Username  string         `gorm:"unique;not null;size:50" json:"username"`
@@ -14,6 +20,7 @@ type User struct {
 	FullName  string         `gorm:"size:100" json:"full_name"`
 	Avatar    string         `json:"avatar"`
 	IsOnline  bool           `gorm:"default:false" json:"is_online"`
+	Status    string         `gorm:"not null;size:20;default:'offline'" json:"status"`
 	LastSeen  *time.Time     `json:"last_seen"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -27,5 +34,12 @@ type UserResponse struct {
 	FullName string     `json:"full_name"`
 	Avatar   string     `json:"avatar"`
 	IsOnline bool       `json:"is_online"`
+	Status   string     `json:"status"`
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+type PresenceResponse struct {
+	UserID   uint       `json:"user_id"`
+	Status   string     `json:"status"`
 	LastSeen *time.Time `json:"last_seen"`
 }
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"regexp"
+	"time"
+)
+
+// ConversationRow is one room of a user's inbox as returned by
+// MessageRepository.GetConversations.
+type ConversationRow struct {
+	RoomID             uint
+	Name               string
+	Slug               string
+	Topic              string
+	OwnerID            uint
+	Visibility         string
+	Kind               string
+	ArchivedAt         *time.Time
+	CreatedAt          time.Time
+	LastMessageID      *uint
+	LastMessageContent *string
+	LastMessageUserID  *uint
+	LastMessageUser    *string
+	LastMessageAt      *time.Time
+	ActivityAt         time.Time
+	UnreadCount        int
+	MentionCount       int
+}
+
+// ConversationCursor points just past the last row of the previous page.
+type ConversationCursor struct {
+	ActivityAt time.Time
+	RoomID     uint
+}
+
+// The inbox is built in one round trip: a lateral join picks each room's
+// latest top-level message, the page is cut by cursor and limit, and only
+// then a second lateral join counts unread messages and mentions after the
+// member's read cursor, so rooms outside the page are never counted.
+const conversationsQuery = `
+SELECT
+	p.room_id, p.name, p.slug, p.topic, p.owner_id, p.visibility, p.kind,
+	p.archived_at, p.created_at,
+	p.last_message_id, p.last_message_content, p.last_message_user_id,
+	p.last_message_user, p.last_message_at, p.activity_at,
+	uc.unread_count,
+	uc.mention_count
+FROM (
+	SELECT
+		r.id AS room_id, r.name, r.slug, r.topic, r.owner_id, r.visibility, r.kind,
+		r.archived_at, r.created_at,
+		lm.id AS last_message_id,
+		lm.content AS last_message_content,
+		lm.user_id AS last_message_user_id,
+		lu.username AS last_message_user,
+		lm.created_at AS last_message_at,
+		COALESCE(lm.created_at, r.created_at) AS activity_at,
+		rm.last_read_message_id
+	FROM room_members rm
+	JOIN rooms r ON r.id = rm.room_id AND r.deleted_at IS NULL
+	LEFT JOIN LATERAL (
+		SELECT m.id, m.content, m.user_id, m.created_at
+		FROM messages m
+		WHERE m.room_id = r.slug AND m.deleted_at IS NULL AND m.thread_root_id IS NULL
+		ORDER BY m.id DESC
+		LIMIT 1
+	) lm ON TRUE
+	LEFT JOIN users lu ON lu.id = lm.user_id
+	WHERE rm.user_id = @user_id
+		AND (@first OR (COALESCE(lm.created_at, r.created_at), r.id) < (@cursor_at, @cursor_room))
+	ORDER BY activity_at DESC, r.id DESC
+	LIMIT @limit
+) p
+LEFT JOIN LATERAL (
+	SELECT
+		COUNT(*) AS unread_count,
+		COUNT(*) FILTER (WHERE m.content ~ @mention) AS mention_count
+	FROM messages m
+	WHERE m.room_id = p.slug
+		AND m.id > p.last_read_message_id
+		AND m.user_id <> @user_id
+		AND m.deleted_at IS NULL
+) uc ON TRUE
+ORDER BY p.activity_at DESC, p.room_id DESC`
+
+// mentionPattern matches @username as a whole word, so @bob does not count
+// @bobby or @bob_smith.
+func mentionPattern(username string) string {
+	return `(^|\W)@` + regexp.QuoteMeta(username) + `(\W|$)`
+}
@@ -1,10 +1,19 @@
 package repositories
 
 import (
+	"errors"
+	"time"
+
 	"github.com/prajapatiomkar/wave-server/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrDuplicateMessage is returned by Create and CreateReply when the sender
+// already stored a message with the same client_msg_id. The message argument
+// is then filled with the stored row.
+var ErrDuplicateMessage = errors.New("duplicate client message id")
+
 type MessageRepository struct {
 	db *gorm.DB
 }
@@ -13,15 +22,40 @@ func NewMessageRepository(db *gorm.DB) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
+// Create stores a message with the next sequence number of its room.
 func (r *MessageRepository) Create(message *models.Message) error {
-	return r.db.Create(message).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSeq(tx, message.RoomID)
+		if err != nil {
+			return err
+		}
+		message.Seq = seq
+		return insertOnce(tx, message)
+	})
+	return r.resolveDuplicate(message, err)
+}
+
+// GetSinceSeq returns a room's messages after seq in sequence order, deleted
+// ones included so a resuming client learns about them too.
+func (r *MessageRepository) GetSinceSeq(roomID string, seq int64, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.
+		Unscoped().
+		Preload("User").
+		Where("room_id = ? AND seq > ?", roomID, seq).
+		Order("seq ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
 }
 
 func (r *MessageRepository) GetByRoom(roomID string, limit, offset int) ([]models.Message, error) {
 	var messages []models.Message
+	// Deleted messages are kept in history as tombstones.
 	err := r.db.
+		Unscoped().
 		Preload("User").
-		Where("room_id = ?", roomID).
+		Where("room_id = ? AND thread_root_id IS NULL", roomID).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -34,3 +68,128 @@ func (r *MessageRepository) GetByID(id uint) (*models.Message, error) {
 	err := r.db.Preload("User").First(&message, id).Error
 	return &message, err
 }
+
+func (r *MessageRepository) GetByIDUnscoped(id uint) (*models.Message, error) {
+	var message models.Message
+	err := r.db.Unscoped().Preload("User").First(&message, id).Error
+	return &message, err
+}
+
+// CreateReply stores a thread reply and bumps the counters on its root.
+func (r *MessageRepository) CreateReply(reply *models.Message) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		seq, err := nextSeq(tx, reply.RoomID)
+		if err != nil {
+			return err
+		}
+		reply.Seq = seq
+		if err := insertOnce(tx, reply); err != nil {
+			return err
+		}
+		return tx.Model(&models.Message{}).
+			Where("id = ?", *reply.ThreadRootID).
+			Updates(map[string]interface{}{
+				"reply_count":   gorm.Expr("reply_count + 1"),
+				"last_reply_at": reply.CreatedAt,
+			}).Error
+	})
+	return r.resolveDuplicate(reply, err)
+}
+
+func (r *MessageRepository) GetThreadReplies(rootID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.
+		Unscoped().
+		Preload("User").
+		Where("thread_root_id = ?", rootID).
+		Order("created_at ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *MessageRepository) SaveEdit(message *models.Message, edit *models.MessageEdit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(edit).Error; err != nil {
+			return err
+		}
+		return tx.Model(message).Updates(map[string]interface{}{
+			"content":   message.Content,
+			"edited_at": message.EditedAt,
+		}).Error
+	})
+}
+
+func (r *MessageRepository) SoftDelete(message *models.Message, deletedBy uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(message).Update("deleted_by", deletedBy).Error; err != nil {
+			return err
+		}
+		return tx.Delete(message).Error
+	})
+}
+
+func (r *MessageRepository) GetConversations(userID uint, username string, cursor *ConversationCursor, limit int) ([]ConversationRow, error) {
+	args := map[string]interface{}{
+		"user_id":     userID,
+		"mention":     mentionPattern(username),
+		"first":       cursor == nil,
+		"cursor_at":   time.Time{},
+		"cursor_room": uint(0),
+		"limit":       limit,
+	}
+	if cursor != nil {
+		args["cursor_at"] = cursor.ActivityAt
+		args["cursor_room"] = cursor.RoomID
+	}
+
+	var rows []ConversationRow
+	err := r.db.Raw(conversationsQuery, args).Scan(&rows).Error
+	return rows, err
+}
+
+// nextSeq increments a room's sequence counter. The row lock it takes is held
+// until the surrounding transaction ends, so sequence numbers are assigned in
+// commit order and a rolled-back insert does not leave a gap.
+func nextSeq(tx *gorm.DB, roomID string) (int64, error) {
+	var seq int64
+	err := tx.Raw("UPDATE rooms SET last_seq = last_seq + 1 WHERE slug = ? RETURNING last_seq", roomID).
+		Scan(&seq).Error
+	return seq, err
+}
+
+// insertOnce inserts a message unless its sender already stored one with the
+// same client_msg_id, in which case the transaction is rolled back so the
+// sequence number is not consumed.
+func insertOnce(tx *gorm.DB, message *models.Message) error {
+	if message.ClientMsgID == "" {
+		return tx.Create(message).Error
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}, {Name: "client_msg_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "client_msg_id <> ''"}}},
+		DoNothing:   true,
+	}).Create(message)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDuplicateMessage
+	}
+	return nil
+}
+
+func (r *MessageRepository) resolveDuplicate(message *models.Message, err error) error {
+	if !errors.Is(err, ErrDuplicateMessage) {
+		return err
+	}
+
+	var existing models.Message
+	if err := r.db.
+		Where("user_id = ? AND client_msg_id = ?", message.UserID, message.ClientMsgID).
+		First(&existing).Error; err != nil {
+		return err
+	}
+	*message = existing
+	return ErrDuplicateMessage
+}
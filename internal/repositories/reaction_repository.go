@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) *ReactionRepository {
+	return &ReactionRepository{db: db}
+}
+
+// Add stores a reaction and reports whether it was new.
+func (r *ReactionRepository) Add(reaction *models.MessageReaction) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Remove deletes a reaction and reports whether it existed.
+func (r *ReactionRepository) Remove(messageID, userID uint, emoji string) (bool, error) {
+	result := r.db.
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&models.MessageReaction{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Summarize aggregates reactions per message and emoji, flagging the ones
+// userID has added, in a single query.
+func (r *ReactionRepository) Summarize(messageIDs []uint, userID uint) (map[uint][]models.ReactionSummary, error) {
+	summaries := make(map[uint][]models.ReactionSummary)
+	if len(messageIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []struct {
+		MessageID uint
+		Emoji     string
+		Count     int
+		Reacted   bool
+	}
+	err := r.db.
+		Model(&models.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, BOOL_OR(user_id = ?) AS reacted", userID).
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("MIN(created_at) ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		summaries[row.MessageID] = append(summaries[row.MessageID], models.ReactionSummary{
+			Emoji:   row.Emoji,
+			Count:   row.Count,
+			Reacted: row.Reacted,
+		})
+	}
+
+	return summaries, nil
+}
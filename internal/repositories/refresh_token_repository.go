@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *RefreshTokenRepository) FindByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return &token, err
+}
+
+// MarkRotated flags a token as used and reports false if it had already
+// been rotated or revoked, so two concurrent refreshes cannot both win.
+func (r *RefreshTokenRepository) MarkRotated(id uint) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ListActiveFamilies returns the families, i.e. sessions, of a user that
+// can still be refreshed.
+func (r *RefreshTokenRepository) ListActiveFamilies(userID uint) ([]string, error) {
+	var families []string
+	err := r.db.Model(&models.RefreshToken{}).
+		Distinct("family_id").
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Pluck("family_id", &families).Error
+	return families, err
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{db: db}
+}
+
+func (r *RevocationRepository) Create(revoked *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(revoked).Error
+}
+
+// ListActiveSince returns unexpired revocations created after since.
+func (r *RevocationRepository) ListActiveSince(since time.Time) ([]models.RevokedToken, error) {
+	var revoked []models.RevokedToken
+	err := r.db.
+		Where("created_at > ? AND expires_at > ?", since, time.Now()).
+		Find(&revoked).Error
+	return revoked, err
+}
+
+func (r *RevocationRepository) DeleteExpired() error {
+	return r.db.Where("expires_at <= ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+)
+
+type RoomMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomMemberRepository(db *gorm.DB) *RoomMemberRepository {
+	return &RoomMemberRepository{db: db}
+}
+
+func (r *RoomMemberRepository) Create(member *models.RoomMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *RoomMemberRepository) Update(member *models.RoomMember) error {
+	return r.db.Save(member).Error
+}
+
+func (r *RoomMemberRepository) Delete(member *models.RoomMember) error {
+	return r.db.Delete(member).Error
+}
+
+func (r *RoomMemberRepository) Find(roomID, userID uint) (*models.RoomMember, error) {
+	var member models.RoomMember
+	err := r.db.Where("room_id = ? AND user_id = ?", roomID, userID).First(&member).Error
+	return &member, err
+}
+
+func (r *RoomMemberRepository) ListByRoom(roomID uint) ([]models.RoomMember, error) {
+	var members []models.RoomMember
+	err := r.db.
+		Preload("User").
+		Where("room_id = ?", roomID).
+		Order("created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *RoomMemberRepository) ListOthersInRooms(roomIDs []uint, userID uint) ([]models.RoomMember, error) {
+	var members []models.RoomMember
+	err := r.db.
+		Preload("User").
+		Where("room_id IN ? AND user_id <> ?", roomIDs, userID).
+		Find(&members).Error
+	return members, err
+}
+
+// AdvanceReadCursor moves a member's read cursor forward and reports whether
+// it moved; cursors never go backwards.
+func (r *RoomMemberRepository) AdvanceReadCursor(roomID, userID, messageID uint, readAt time.Time) (bool, error) {
+	result := r.db.
+		Model(&models.RoomMember{}).
+		Where("room_id = ? AND user_id = ? AND last_read_message_id < ?", roomID, userID, messageID).
+		Updates(map[string]interface{}{
+			"last_read_message_id": messageID,
+			"last_read_at":         readAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+)
+
+type RoomRepository struct {
+	db *gorm.DB
+}
+
+func NewRoomRepository(db *gorm.DB) *RoomRepository {
+	return &RoomRepository{db: db}
+}
+
+func (r *RoomRepository) Create(room *models.Room) error {
+	return r.db.Create(room).Error
+}
+
+// CreateWithMembers creates a room and its initial members in one
+// transaction, so a room never exists without them.
+func (r *RoomRepository) CreateWithMembers(room *models.Room, members []models.RoomMember) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(room).Error; err != nil {
+			return err
+		}
+		for i := range members {
+			members[i].RoomID = room.ID
+		}
+		return tx.Create(&members).Error
+	})
+}
+
+func (r *RoomRepository) Update(room *models.Room) error {
+	return r.db.Save(room).Error
+}
+
+func (r *RoomRepository) FindBySlug(slug string) (*models.Room, error) {
+	var room models.Room
+	err := r.db.Where("slug = ?", slug).First(&room).Error
+	return &room, err
+}
+
+func (r *RoomRepository) ListVisible(userID uint, limit, offset int) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.
+		Where("archived_at IS NULL AND kind = ?", models.RoomKindChannel).
+		Where("visibility = ? OR id IN (?)",
+			models.RoomVisibilityPublic,
+			r.db.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID),
+		).
+		Order("name ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&rooms).Error
+	return rooms, err
+}
+
+func (r *RoomRepository) ListDirectForUser(userID uint) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.
+		Where("kind = ?", models.RoomKindDirect).
+		Where("id IN (?)", r.db.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID)).
+		Order("updated_at DESC").
+		Find(&rooms).Error
+	return rooms, err
+}
+
+func (r *RoomRepository) ListSlugsForMember(userID uint) ([]string, error) {
+	var slugs []string
+	err := r.db.
+		Model(&models.Room{}).
+		Where("id IN (?)", r.db.Model(&models.RoomMember{}).Select("room_id").Where("user_id = ?", userID)).
+		Pluck("slug", &slugs).Error
+	return slugs, err
+}
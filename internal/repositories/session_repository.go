@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *SessionRepository) FindActive(id string, userID uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		First(&session).Error
+	return &session, err
+}
+
+// ListActiveByUser returns the sessions that can still be refreshed: not
+// revoked, and holding an unused refresh token that has not expired.
+func (r *SessionRepository) ListActiveByUser(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Where("EXISTS (?)", r.db.Model(&models.RefreshToken{}).
+			Select("1").
+			Where("refresh_tokens.family_id = sessions.id").
+			Where("refresh_tokens.rotated_at IS NULL AND refresh_tokens.revoked_at IS NULL").
+			Where("refresh_tokens.expires_at > ?", time.Now()),
+		).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *SessionRepository) Touch(id string, at time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+func (r *SessionRepository) Revoke(id string) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
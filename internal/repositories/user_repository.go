@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/prajapatiomkar/wave-server/internal/models"
 	"gorm.io/gorm"
 )
@@ -34,3 +36,17 @@ func (r *UserRepository) FindByID(id uint) (*models.User, error) {
 	err := r.db.First(&user, id).Error
 	return &user, err
 }
+
+func (r *UserRepository) FindByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r *UserRepository) UpdatePresence(id uint, status string, lastSeen time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_online": status != models.UserStatusOffline,
+		"status":    status,
+		"last_seen": lastSeen,
+	}).Error
+}
@@ -1,22 +1,44 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"os"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/prajapatiomkar/wave-server/config"
+	"github.com/prajapatiomkar/wave-server/internal/auth"
 	"github.com/prajapatiomkar/wave-server/internal/models"
 	"github.com/prajapatiomkar/wave-server/internal/repositories"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	userRepo *repositories.UserRepository
+	userRepo         *repositories.UserRepository
+	refreshTokenRepo *repositories.RefreshTokenRepository
+	sessionRepo      *repositories.SessionRepository
+	revocations      *RevocationService
+	signer           *auth.TokenSigner
+	cfg              config.AuthConfig
 }
 
-func NewAuthService(userRepo *repositories.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo *repositories.UserRepository, refreshTokenRepo *repositories.RefreshTokenRepository, sessionRepo *repositories.SessionRepository, revocations *RevocationService, signer *auth.TokenSigner, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		revocations:      revocations,
+		signer:           signer,
+		cfg:              cfg,
+	}
+}
+
+// ClientInfo describes the device a session is started from.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
 }
 
 type RegisterRequest struct {
@@ -31,12 +53,18 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type AuthResponse struct {
-	User  models.UserResponse `json:"user"`
-	Token string              `json:"token"`
+	User         models.UserResponse `json:"user"`
+	Token        string              `json:"token"`
+	ExpiresAt    time.Time           `json:"expires_at"`
+	RefreshToken string              `json:"refresh_token"`
 }
 
-func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(req *RegisterRequest, client ClientInfo) (*AuthResponse, error) {
 	if _, err := s.userRepo.FindByEmail(req.Email); err == nil {
 		return nil, errors.New("email already registered")
 	}
@@ -61,18 +89,10 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		return nil, errors.New("failed to create user")
 	}
 
-	token, err := s.generateToken(user.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
-	}
-
-	return &AuthResponse{
-		User:  s.toUserResponse(user),
-		Token: token,
-	}, nil
+	return s.startSession(user, client)
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
+func (s *AuthService) Login(req *LoginRequest, client ClientInfo) (*AuthResponse, error) {
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		return nil, errors.New("invalid credentials")
@@ -82,15 +102,7 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("invalid credentials")
 	}
 
-	token, err := s.generateToken(user.ID)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
-	}
-
-	return &AuthResponse{
-		User:  s.toUserResponse(user),
-		Token: token,
-	}, nil
+	return s.startSession(user, client)
 }
 
 func (s *AuthService) GetUserByID(userID uint) (*models.UserResponse, error) {
@@ -99,21 +111,195 @@ func (s *AuthService) GetUserByID(userID uint) (*models.UserResponse, error) {
 		return nil, errors.New("user not found")
 	}
 
-	userResp := s.toUserResponse(user)
+	userResp := toUserResponse(user)
 	return &userResp, nil
 }
 
-func (s *AuthService) generateToken(userID uint) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// Each refresh token works once; presenting one that was already rotated
+// means it leaked, so the whole family is revoked and the user must log in
+// again.
+func (s *AuthService) Refresh(req *RefreshRequest) (*AuthResponse, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(hashToken(req.RefreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := s.refreshTokenRepo.MarkRotated(stored.ID)
+	if err != nil {
+		return nil, errors.New("failed to refresh token")
+	}
+	if !rotated {
+		if err := s.revokeSession(stored.UserID, stored.FamilyID); err != nil {
+			return nil, errors.New("failed to refresh token")
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.sessionRepo.Touch(stored.FamilyID, time.Now()); err != nil {
+		return nil, errors.New("failed to refresh token")
+	}
+
+	return s.issueTokens(user, stored.FamilyID)
+}
+
+// ListSessions returns the user's active sessions, marking the one the
+// request was made with. Connection counts are filled in by the caller.
+func (s *AuthService) ListSessions(userID uint, currentSessionID string) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return response, nil
+}
+
+// RevokeSession logs out one of the user's own sessions, e.g. a lost device.
+func (s *AuthService) RevokeSession(userID uint, sessionID string) error {
+	if _, err := s.sessionRepo.FindActive(sessionID, userID); err != nil {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(userID, sessionID)
+}
+
+// Logout ends one session: its refresh tokens stop working and access
+// tokens already issued for it are rejected until they expire.
+func (s *AuthService) Logout(userID uint, sessionID string) error {
+	if sessionID == "" {
+		return ErrNoSession
 	}
+	return s.revokeSession(userID, sessionID)
+}
+
+// LogoutAll ends every session of a user, including the current one.
+func (s *AuthService) LogoutAll(userID uint, sessionID string) error {
+	sessions, err := s.refreshTokenRepo.ListActiveFamilies(userID)
+	if err != nil {
+		return errors.New("failed to list sessions")
+	}
+	if sessionID != "" {
+		sessions = append(sessions, sessionID)
+	}
+
+	for _, session := range sessions {
+		if err := s.revokeSession(userID, session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) revokeSession(userID uint, sessionID string) error {
+	if err := s.refreshTokenRepo.RevokeFamily(sessionID); err != nil {
+		return errors.New("failed to revoke session")
+	}
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return errors.New("failed to revoke session")
+	}
+	// No access token for the session verifies for longer than one access
+	// token TTL plus the clock leeway, so the revocation only has to be
+	// remembered that long.
+	if err := s.revocations.Revoke(sessionID, userID, time.Now().Add(s.cfg.TokenTTL+s.cfg.Leeway)); err != nil {
+		return errors.New("failed to revoke session")
+	}
+	return nil
+}
+
+// startSession records a new login and issues its first tokens.
+func (s *AuthService) startSession(user *models.User, client ClientInfo) (*AuthResponse, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	userAgent := client.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := time.Now()
+	if err := s.sessionRepo.Create(&models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	return s.issueTokens(user, sessionID)
+}
+
+// issueTokens returns a short-lived access token and a refresh token in the
+// given family. The family ID is also the session ID carried by the access
+// token.
+func (s *AuthService) issueTokens(user *models.User, familyID string) (*AuthResponse, error) {
+	expiresAt := time.Now().Add(s.cfg.TokenTTL)
+	token, err := s.signer.Sign(user.ID, familyID, expiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	if err := s.refreshTokenRepo.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.cfg.RefreshTTL),
+	}); err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &AuthResponse{
+		User:         toUserResponse(user),
+		Token:        token,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// hashToken is how refresh tokens are stored; only the client ever holds the
+// token itself.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (s *AuthService) toUserResponse(user *models.User) models.UserResponse {
+func toUserResponse(user *models.User) models.UserResponse {
 	return models.UserResponse{
 		ID:       user.ID,
 		Username: user.Username,
@@ -121,6 +307,7 @@ func (s *AuthService) toUserResponse(user *models.User) models.UserResponse {
 		FullName: user.FullName,
 		Avatar:   user.Avatar,
 		IsOnline: user.IsOnline,
+		Status:   user.Status,
 		LastSeen: user.LastSeen,
 	}
 }
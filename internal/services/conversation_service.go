@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"github.com/prajapatiomkar/wave-server/internal/repositories"
+)
+
+type ConversationService struct {
+	messageRepo *repositories.MessageRepository
+	userRepo    *repositories.UserRepository
+}
+
+func NewConversationService(messageRepo *repositories.MessageRepository, userRepo *repositories.UserRepository) *ConversationService {
+	return &ConversationService{
+		messageRepo: messageRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// ListConversations returns the caller's rooms ordered by latest activity,
+// along with an opaque cursor for the next page (empty on the last page).
+func (s *ConversationService) ListConversations(userID uint, cursor string, limit int) ([]models.ConversationResponse, string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, "", ErrUserNotFound
+	}
+
+	var after *repositories.ConversationCursor
+	if cursor != "" {
+		after, err = decodeConversationCursor(cursor)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	rows, err := s.messageRepo.GetConversations(userID, user.Username, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeConversationCursor(&repositories.ConversationCursor{
+			ActivityAt: last.ActivityAt,
+			RoomID:     last.RoomID,
+		})
+	}
+
+	response := make([]models.ConversationResponse, 0, len(rows))
+	for _, row := range rows {
+		conversation := models.ConversationResponse{
+			Room: models.RoomResponse{
+				ID:         row.RoomID,
+				Name:       row.Name,
+				Slug:       row.Slug,
+				Topic:      row.Topic,
+				OwnerID:    row.OwnerID,
+				Visibility: row.Visibility,
+				Kind:       row.Kind,
+				ArchivedAt: row.ArchivedAt,
+				CreatedAt:  row.CreatedAt,
+			},
+			UnreadCount:    row.UnreadCount,
+			MentionCount:   row.MentionCount,
+			LastActivityAt: row.ActivityAt,
+		}
+
+		if row.LastMessageID != nil {
+			conversation.LastMessage = &models.MessagePreview{
+				ID:        *row.LastMessageID,
+				Content:   *row.LastMessageContent,
+				UserID:    *row.LastMessageUserID,
+				CreatedAt: *row.LastMessageAt,
+			}
+			if row.LastMessageUser != nil {
+				conversation.LastMessage.Username = *row.LastMessageUser
+			}
+		}
+
+		response = append(response, conversation)
+	}
+
+	return response, nextCursor, nil
+}
+
+func encodeConversationCursor(cursor *repositories.ConversationCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.ActivityAt.UnixMicro(), cursor.RoomID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeConversationCursor(cursor string) (*repositories.ConversationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	activity, roomID, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(activity, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(roomID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories.ConversationCursor{
+		ActivityAt: time.UnixMicro(micros),
+		RoomID:     uint(id),
+	}, nil
+}
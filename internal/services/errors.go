@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/prajapatiomkar/wave-server/internal/websocket"
+)
+
+var (
+	ErrRoomNotFound = errors.New("room not found")
+	ErrRoomArchived = errors.New("room is archived")
+	ErrSlugTaken    = errors.New("room slug already taken")
+	ErrInvalidSlug  = errors.New("slug may only contain lowercase letters, digits and dashes")
+	ErrForbidden    = errors.New("you are not allowed to do that")
+	ErrNotMember    = errors.New("you are not a member of this room")
+	ErrOwnerLeave   = errors.New("the room owner cannot leave the room")
+	ErrUserNotFound = errors.New("user not found")
+	ErrSelfDirect   = errors.New("cannot start a direct conversation with yourself")
+
+	ErrMessageNotFound = errors.New("message not found")
+	ErrEmptyContent    = errors.New("content required")
+	ErrContentTooLong  = errors.New("content exceeds 512 bytes")
+	ErrInvalidEmoji    = errors.New("emoji must be between 1 and 64 characters")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+
+	ErrInvalidClientMsgID = errors.New("client_msg_id must be at most 64 characters")
+
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; please log in again")
+	ErrNoSession           = errors.New("token is not bound to a session")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// frameError tags an error returned to the hub with the code of the error
+// frame the client receives. Anything unrecognised is a storage failure.
+func frameError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, websocket.ErrDuplicateMessage):
+		return err
+	case errors.Is(err, ErrRoomNotFound), errors.Is(err, ErrUserNotFound),
+		errors.Is(err, ErrMessageNotFound):
+		return websocket.NewError(websocket.ErrorCodeNotFound, err)
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrNotMember),
+		errors.Is(err, ErrRoomArchived):
+		return websocket.NewError(websocket.ErrorCodeForbidden, err)
+	case errors.Is(err, ErrContentTooLong):
+		return websocket.NewError(websocket.ErrorCodeTooLarge, err)
+	case errors.Is(err, ErrEmptyContent), errors.Is(err, ErrInvalidEmoji),
+		errors.Is(err, ErrInvalidClientMsgID):
+		return websocket.NewError(websocket.ErrorCodeInvalidRequest, err)
+	default:
+		return websocket.NewError(websocket.ErrorCodePersistFailed, err)
+	}
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/prajapatiomkar/wave-server/internal/models"
 	"github.com/prajapatiomkar/wave-server/internal/repositories"
@@ -10,19 +12,75 @@ import (
 )
 
 type MessageService struct {
-	messageRepo *repositories.MessageRepository
-	userRepo    *repositories.UserRepository
+	messageRepo  *repositories.MessageRepository
+	userRepo     *repositories.UserRepository
+	reactionRepo *repositories.ReactionRepository
+	roomService  *RoomService
 }
 
-func NewMessageService(messageRepo *repositories.MessageRepository, userRepo *repositories.UserRepository) *MessageService {
+func NewMessageService(messageRepo *repositories.MessageRepository, userRepo *repositories.UserRepository, reactionRepo *repositories.ReactionRepository, roomService *RoomService) *MessageService {
 	return &MessageService{
-		messageRepo: messageRepo,
-		userRepo:    userRepo,
+		messageRepo:  messageRepo,
+		userRepo:     userRepo,
+		reactionRepo: reactionRepo,
+		roomService:  roomService,
 	}
 }
 
+// maxContentLength matches the websocket frame limit for new messages, so
+// an edit can never grow a message past what could have been sent.
+const maxContentLength = 512
+
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required,max=512"`
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+type MarkReadRequest struct {
+	MessageID uint `json:"message_id" binding:"required"`
+}
+
 func (s *MessageService) HandleMessage(msg *websocket.IncomingMessage) (*websocket.OutgoingMessage, error) {
-	if msg.Type == "typing" {
+	event, err := s.handleMessage(msg)
+	return event, frameError(err)
+}
+
+func (s *MessageService) handleMessage(msg *websocket.IncomingMessage) (*websocket.OutgoingMessage, error) {
+	switch msg.Type {
+	case "edit":
+		return s.EditMessage(msg.MessageID, msg.UserID, msg.Content)
+	case "delete":
+		return s.DeleteMessage(msg.MessageID, msg.UserID)
+	case "react":
+		return s.AddReaction(msg.MessageID, msg.UserID, msg.Emoji)
+	case "unreact":
+		return s.RemoveReaction(msg.MessageID, msg.UserID, msg.Emoji)
+	case "read":
+		return s.MarkRead(msg.RoomID, msg.UserID, msg.MessageID)
+	}
+
+	room, member, err := s.roomService.RequireMember(msg.RoomID, msg.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, ErrRoomArchived
+	}
+
+	if !member.CanPost() {
+		return nil, ErrForbidden
+	}
+
+	if len(msg.ClientMsgID) > 64 {
+		return nil, ErrInvalidClientMsgID
+	}
+
+	switch msg.Type {
+	case "typing":
 		return &websocket.OutgoingMessage{
 			Type:      "typing",
 			Content:   msg.Content,
@@ -31,16 +89,20 @@ func (s *MessageService) HandleMessage(msg *websocket.IncomingMessage) (*websock
 			Username:  msg.Username,
 			CreatedAt: time.Now(),
 		}, nil
+	case "reply":
+		return s.createReply(msg)
 	}
 
 	message := &models.Message{
-		RoomID:  msg.RoomID,
-		UserID:  msg.UserID,
-		Content: msg.Content,
-		Type:    "text",
+		RoomID:      msg.RoomID,
+		UserID:      msg.UserID,
+		ClientMsgID: msg.ClientMsgID,
+		Content:     msg.Content,
+		Type:        "text",
 	}
 
-	if err := s.messageRepo.Create(message); err != nil {
+	created := s.messageRepo.Create(message)
+	if created != nil && !errors.Is(created, repositories.ErrDuplicateMessage) {
 		return nil, errors.New("failed to save message")
 	}
 
@@ -49,42 +111,411 @@ func (s *MessageService) HandleMessage(msg *websocket.IncomingMessage) (*websock
 		return nil, errors.New("user not found")
 	}
 
+	return toOutgoingMessage("message", message, user), duplicateErr(created)
+}
+
+// createReply stores a thread reply and returns a thread_updated event that
+// carries the reply together with the root's new reply count, so clients can
+// update the thread without the reply landing in the main timeline.
+func (s *MessageService) createReply(msg *websocket.IncomingMessage) (*websocket.OutgoingMessage, error) {
+	parent, err := s.messageRepo.GetByID(msg.ParentID)
+	if err != nil || parent.RoomID != msg.RoomID {
+		return nil, ErrMessageNotFound
+	}
+
+	rootID := parent.ID
+	if parent.ThreadRootID != nil {
+		rootID = *parent.ThreadRootID
+	}
+
+	reply := &models.Message{
+		RoomID:       msg.RoomID,
+		UserID:       msg.UserID,
+		ClientMsgID:  msg.ClientMsgID,
+		Content:      msg.Content,
+		Type:         "text",
+		ParentID:     &parent.ID,
+		ThreadRootID: &rootID,
+	}
+
+	created := s.messageRepo.CreateReply(reply)
+	if created != nil && !errors.Is(created, repositories.ErrDuplicateMessage) {
+		return nil, errors.New("failed to save message")
+	}
+
+	root, err := s.messageRepo.GetByID(rootID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	user, err := s.userRepo.FindByID(msg.UserID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	event := toOutgoingMessage("thread_updated", reply, user)
+	event.ReplyCount = root.ReplyCount
+	event.LastReplyAt = root.LastReplyAt
+	return event, duplicateErr(created)
+}
+
+// duplicateErr tells the hub that a retried send was already stored, so the
+// sender is acked again without the message being broadcast twice.
+func duplicateErr(err error) error {
+	if errors.Is(err, repositories.ErrDuplicateMessage) {
+		return websocket.ErrDuplicateMessage
+	}
+	return nil
+}
+
+func (s *MessageService) GetThread(rootID, userID uint) (*models.ThreadResponse, error) {
+	root, err := s.messageRepo.GetByIDUnscoped(rootID)
+	if err != nil || root.ThreadRootID != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if _, _, err := s.roomService.RequireMember(root.RoomID, userID); err != nil {
+		return nil, err
+	}
+
+	replies, err := s.messageRepo.GetThreadReplies(rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ThreadResponse{
+		Root:    toMessageResponse(root),
+		Replies: make([]models.MessageResponse, 0, len(replies)),
+	}
+	for i := range replies {
+		response.Replies = append(response.Replies, toMessageResponse(&replies[i]))
+	}
+
+	thread := append([]models.MessageResponse{response.Root}, response.Replies...)
+	if err := s.attachReactions(thread, userID); err != nil {
+		return nil, err
+	}
+	response.Root, response.Replies = thread[0], thread[1:]
+
+	return response, nil
+}
+
+func (s *MessageService) EditMessage(messageID, userID uint, content string) (*websocket.OutgoingMessage, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyContent
+	}
+	if len(content) > maxContentLength {
+		return nil, ErrContentTooLong
+	}
+
+	message, err := s.messageRepo.GetByID(messageID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if message.UserID != userID {
+		return nil, ErrForbidden
+	}
+
+	room, _, err := s.roomService.RequireMember(message.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, ErrRoomArchived
+	}
+
+	now := time.Now()
+	edit := &models.MessageEdit{
+		MessageID: message.ID,
+		UserID:    userID,
+		Content:   message.Content,
+	}
+	message.Content = content
+	message.EditedAt = &now
+
+	if err := s.messageRepo.SaveEdit(message, edit); err != nil {
+		return nil, errors.New("failed to save edit")
+	}
+
+	event := toOutgoingMessage("message_edited", message, &message.User)
+	// Only events that create a message advance the room sequence; an edit
+	// carrying the original seq would be dropped as a duplicate.
+	event.Seq = 0
+	return event, nil
+}
+
+// DeleteMessage soft-deletes a message. Authors can delete their own
+// messages; room owners and admins can delete anyone's.
+func (s *MessageService) DeleteMessage(messageID, userID uint) (*websocket.OutgoingMessage, error) {
+	message, err := s.messageRepo.GetByID(messageID)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	_, member, err := s.roomService.RequireMember(message.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserID != userID && !member.CanModerate() {
+		return nil, ErrForbidden
+	}
+
+	if err := s.messageRepo.SoftDelete(message, userID); err != nil {
+		return nil, errors.New("failed to delete message")
+	}
+
 	return &websocket.OutgoingMessage{
 		ID:        message.ID,
-		Type:      "message",
-		Content:   message.Content,
+		Type:      "message_deleted",
 		RoomID:    message.RoomID,
-		UserID:    message.UserID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// AddReaction returns a reaction_added event, or nil when the user had
+// already reacted with that emoji.
+func (s *MessageService) AddReaction(messageID, userID uint, emoji string) (*websocket.OutgoingMessage, error) {
+	message, user, err := s.reactionTarget(messageID, userID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.reactionRepo.Add(&models.MessageReaction{
+		MessageID: message.ID,
+		UserID:    userID,
+		Emoji:     emoji,
+	})
+	if err != nil {
+		return nil, errors.New("failed to save reaction")
+	}
+	if !added {
+		return nil, nil
+	}
+
+	return reactionEvent("reaction_added", message, user, emoji), nil
+}
+
+// RemoveReaction returns a reaction_removed event, or nil when there was no
+// such reaction to remove.
+func (s *MessageService) RemoveReaction(messageID, userID uint, emoji string) (*websocket.OutgoingMessage, error) {
+	message, user, err := s.reactionTarget(messageID, userID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.reactionRepo.Remove(message.ID, userID, emoji)
+	if err != nil {
+		return nil, errors.New("failed to remove reaction")
+	}
+	if !removed {
+		return nil, nil
+	}
+
+	return reactionEvent("reaction_removed", message, user, emoji), nil
+}
+
+func (s *MessageService) reactionTarget(messageID, userID uint, emoji string) (*models.Message, *models.User, error) {
+	if emoji == "" || utf8.RuneCountInString(emoji) > 64 {
+		return nil, nil, ErrInvalidEmoji
+	}
+
+	message, err := s.messageRepo.GetByID(messageID)
+	if err != nil {
+		return nil, nil, ErrMessageNotFound
+	}
+
+	room, member, err := s.roomService.RequireMember(message.RoomID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, nil, ErrRoomArchived
+	}
+
+	if !member.CanPost() {
+		return nil, nil, ErrForbidden
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, nil, ErrUserNotFound
+	}
+
+	return message, user, nil
+}
+
+func (s *MessageService) attachReactions(messages []models.MessageResponse, userID uint) error {
+	messageIDs := make([]uint, 0, len(messages))
+	for _, msg := range messages {
+		messageIDs = append(messageIDs, msg.ID)
+	}
+
+	summaries, err := s.reactionRepo.Summarize(messageIDs, userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range messages {
+		if reactions, ok := summaries[messages[i].ID]; ok {
+			messages[i].Reactions = reactions
+		} else {
+			messages[i].Reactions = []models.ReactionSummary{}
+		}
+	}
+
+	return nil
+}
+
+// MarkRead records that a user has read a room up to messageID and returns a
+// read_receipt event, or nil when the cursor did not move.
+func (s *MessageService) MarkRead(roomID string, userID, messageID uint) (*websocket.OutgoingMessage, error) {
+	message, err := s.messageRepo.GetByIDUnscoped(messageID)
+	if err != nil || message.RoomID != roomID {
+		return nil, ErrMessageNotFound
+	}
+
+	member, err := s.roomService.MarkRead(roomID, userID, messageID)
+	if err != nil || member == nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return &websocket.OutgoingMessage{
+		ID:        messageID,
+		Type:      "read_receipt",
+		RoomID:    roomID,
+		UserID:    userID,
 		Username:  user.Username,
-		Avatar:    user.Avatar,
-		CreatedAt: message.CreatedAt,
+		CreatedAt: *member.LastReadAt,
 	}, nil
 }
 
-func (s *MessageService) GetMessageHistory(roomID string, limit, offset int) ([]models.MessageResponse, error) {
+// MessagesSince returns the events a client resuming after sinceSeq missed,
+// shaped exactly as they were broadcast live.
+func (s *MessageService) MessagesSince(roomID string, sinceSeq int64, limit int) ([]*websocket.OutgoingMessage, error) {
+	messages, err := s.messageRepo.GetSinceSeq(roomID, sinceSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*websocket.OutgoingMessage, 0, len(messages))
+	for i := range messages {
+		message := &messages[i]
+		switch {
+		case message.DeletedAt.Valid:
+			events = append(events, &websocket.OutgoingMessage{
+				ID:        message.ID,
+				Type:      "message_deleted",
+				RoomID:    message.RoomID,
+				Seq:       message.Seq,
+				CreatedAt: message.DeletedAt.Time,
+			})
+		case message.ThreadRootID != nil:
+			events = append(events, toOutgoingMessage("thread_updated", message, &message.User))
+		default:
+			events = append(events, toOutgoingMessage("message", message, &message.User))
+		}
+	}
+
+	return events, nil
+}
+
+func (s *MessageService) AuthorizeRoom(roomID string, userID uint) error {
+	_, _, err := s.roomService.RequireMember(roomID, userID)
+	return frameError(err)
+}
+
+func (s *MessageService) GetMessageHistory(roomID string, userID uint, limit, offset int) ([]models.MessageResponse, error) {
+	if _, _, err := s.roomService.RequireMember(roomID, userID); err != nil {
+		return nil, err
+	}
+
 	messages, err := s.messageRepo.GetByRoom(roomID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
-	var response []models.MessageResponse
-	for _, msg := range messages {
-		response = append(response, models.MessageResponse{
-			ID:        msg.ID,
-			RoomID:    msg.RoomID,
-			UserID:    msg.UserID,
-			Content:   msg.Content,
-			Type:      msg.Type,
-			CreatedAt: msg.CreatedAt,
-			User: models.UserResponse{
-				ID:       msg.User.ID,
-				Username: msg.User.Username,
-				Email:    msg.User.Email,
-				FullName: msg.User.FullName,
-				Avatar:   msg.User.Avatar,
-			},
-		})
+	response := make([]models.MessageResponse, 0, len(messages))
+	for i := range messages {
+		response = append(response, toMessageResponse(&messages[i]))
+	}
+
+	if err := s.attachReactions(response, userID); err != nil {
+		return nil, err
 	}
 
 	return response, nil
 }
+
+func toOutgoingMessage(eventType string, message *models.Message, user *models.User) *websocket.OutgoingMessage {
+	return &websocket.OutgoingMessage{
+		ID:           message.ID,
+		Type:         eventType,
+		Content:      message.Content,
+		RoomID:       message.RoomID,
+		Seq:          message.Seq,
+		ClientMsgID:  message.ClientMsgID,
+		UserID:       message.UserID,
+		Username:     user.Username,
+		Avatar:       user.Avatar,
+		ParentID:     message.ParentID,
+		ThreadRootID: message.ThreadRootID,
+		EditedAt:     message.EditedAt,
+		CreatedAt:    message.CreatedAt,
+	}
+}
+
+func reactionEvent(eventType string, message *models.Message, user *models.User, emoji string) *websocket.OutgoingMessage {
+	return &websocket.OutgoingMessage{
+		ID:        message.ID,
+		Type:      eventType,
+		RoomID:    message.RoomID,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	}
+}
+
+func toMessageResponse(msg *models.Message) models.MessageResponse {
+	response := models.MessageResponse{
+		ID:           msg.ID,
+		RoomID:       msg.RoomID,
+		Seq:          msg.Seq,
+		ClientMsgID:  msg.ClientMsgID,
+		UserID:       msg.UserID,
+		Type:         msg.Type,
+		ParentID:     msg.ParentID,
+		ThreadRootID: msg.ThreadRootID,
+		ReplyCount:   msg.ReplyCount,
+		LastReplyAt:  msg.LastReplyAt,
+		CreatedAt:    msg.CreatedAt,
+	}
+
+	// Deleted messages are returned as tombstones without content or author
+	// details so clients can render a placeholder in their place.
+	if msg.DeletedAt.Valid {
+		response.DeletedAt = &msg.DeletedAt.Time
+		return response
+	}
+
+	response.Content = msg.Content
+	response.EditedAt = msg.EditedAt
+	response.User = models.UserResponse{
+		ID:       msg.User.ID,
+		Username: msg.User.Username,
+		Email:    msg.User.Email,
+		FullName: msg.User.FullName,
+		Avatar:   msg.User.Avatar,
+	}
+	return response
+}
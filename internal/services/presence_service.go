@@ -0,0 +1,63 @@
+package services
+
+import (
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"github.com/prajapatiomkar/wave-server/internal/repositories"
+)
+
+type PresenceService struct {
+	userRepo *repositories.UserRepository
+	roomRepo *repositories.RoomRepository
+}
+
+func NewPresenceService(userRepo *repositories.UserRepository, roomRepo *repositories.RoomRepository) *PresenceService {
+	return &PresenceService{
+		userRepo: userRepo,
+		roomRepo: roomRepo,
+	}
+}
+
+// SetPresence persists a user's presence and returns their stored username
+// and the rooms, direct conversations included, that should hear about the
+// change.
+func (s *PresenceService) SetPresence(userID uint, status string) (string, []string, error) {
+	if err := s.userRepo.UpdatePresence(userID, status, time.Now()); err != nil {
+		return "", nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	roomIDs, err := s.roomRepo.ListSlugsForMember(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return user.Username, roomIDs, nil
+}
+
+func (s *PresenceService) GetPresence(userIDs []uint) ([]models.PresenceResponse, error) {
+	users, err := s.userRepo.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.PresenceResponse, 0, len(users))
+	for _, user := range users {
+		status := user.Status
+		if status == "" {
+			status = models.UserStatusOffline
+		}
+		response = append(response, models.PresenceResponse{
+			UserID:   user.ID,
+			Status:   status,
+			LastSeen: user.LastSeen,
+		})
+	}
+
+	return response, nil
+}
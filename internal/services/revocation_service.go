@@ -0,0 +1,127 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"github.com/prajapatiomkar/wave-server/internal/repositories"
+)
+
+// revocationSync is how often revocations made on other instances are
+// pulled into the local cache.
+const revocationSync = 10 * time.Second
+
+// RevocationService answers whether a session or token has been revoked
+// from an in-memory copy of the revoked_tokens table, so authenticating a
+// request never waits on the database.
+type RevocationService struct {
+	revocationRepo *repositories.RevocationRepository
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time
+	lastSync time.Time
+	onRevoke []func(id string)
+}
+
+func NewRevocationService(revocationRepo *repositories.RevocationRepository) *RevocationService {
+	return &RevocationService{
+		revocationRepo: revocationRepo,
+		revoked:        make(map[string]time.Time),
+	}
+}
+
+// Start loads the current revocations and keeps the cache in sync.
+func (s *RevocationService) Start() error {
+	if err := s.sync(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(revocationSync)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.sync(); err != nil {
+				log.Printf("❌ Error syncing revocations: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// OnRevoke registers fn to run for every revoked ID, whether it was revoked
+// here or picked up from another instance.
+func (s *RevocationService) OnRevoke(fn func(id string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRevoke = append(s.onRevoke, fn)
+}
+
+func (s *RevocationService) Revoke(id string, userID uint, expiresAt time.Time) error {
+	if err := s.revocationRepo.Create(&models.RevokedToken{
+		ID:        id,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	s.add(id, expiresAt)
+	return nil
+}
+
+func (s *RevocationService) IsRevoked(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, ok := s.revoked[id]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (s *RevocationService) sync() error {
+	s.mu.RLock()
+	// Overlap the window so rows committed slightly out of order are not
+	// missed; add ignores the ones already cached.
+	since := s.lastSync.Add(-revocationSync)
+	s.mu.RUnlock()
+
+	now := time.Now()
+	revoked, err := s.revocationRepo.ListActiveSince(since)
+	if err != nil {
+		return err
+	}
+	for _, r := range revoked {
+		s.add(r.ID, r.ExpiresAt)
+	}
+
+	s.mu.Lock()
+	s.lastSync = now
+	for id, expiresAt := range s.revoked {
+		if now.After(expiresAt) {
+			delete(s.revoked, id)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.revocationRepo.DeleteExpired()
+}
+
+func (s *RevocationService) add(id string, expiresAt time.Time) {
+	s.mu.Lock()
+	_, known := s.revoked[id]
+	s.revoked[id] = expiresAt
+	callbacks := s.onRevoke
+	s.mu.Unlock()
+
+	if known {
+		return
+	}
+	for _, fn := range callbacks {
+		fn(id)
+	}
+}
@@ -0,0 +1,511 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prajapatiomkar/wave-server/internal/models"
+	"github.com/prajapatiomkar/wave-server/internal/repositories"
+)
+
+const directSlugPrefix = "dm-"
+
+var (
+	slugPattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,99}$`)
+	slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+type RoomService struct {
+	roomRepo   *repositories.RoomRepository
+	memberRepo *repositories.RoomMemberRepository
+	userRepo   *repositories.UserRepository
+}
+
+func NewRoomService(roomRepo *repositories.RoomRepository, memberRepo *repositories.RoomMemberRepository, userRepo *repositories.UserRepository) *RoomService {
+	return &RoomService{
+		roomRepo:   roomRepo,
+		memberRepo: memberRepo,
+		userRepo:   userRepo,
+	}
+}
+
+type CreateRoomRequest struct {
+	Name       string `json:"name" binding:"required,min=2,max=100"`
+	Slug       string `json:"slug" binding:"omitempty,max=100"`
+	Topic      string `json:"topic" binding:"max=255"`
+	Visibility string `json:"visibility" binding:"omitempty,oneof=public private"`
+}
+
+type UpdateRoomRequest struct {
+	Name       *string `json:"name" binding:"omitempty,min=2,max=100"`
+	Topic      *string `json:"topic" binding:"omitempty,max=255"`
+	Visibility *string `json:"visibility" binding:"omitempty,oneof=public private"`
+}
+
+type InviteMemberRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin member guest"`
+}
+
+func (s *RoomService) CreateRoom(ownerID uint, req *CreateRoomRequest) (*models.RoomResponse, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !slugPattern.MatchString(slug) || strings.HasPrefix(slug, directSlugPrefix) {
+		return nil, ErrInvalidSlug
+	}
+
+	if _, err := s.roomRepo.FindBySlug(slug); err == nil {
+		return nil, ErrSlugTaken
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = models.RoomVisibilityPublic
+	}
+
+	room := &models.Room{
+		Name:       req.Name,
+		Slug:       slug,
+		Topic:      req.Topic,
+		OwnerID:    ownerID,
+		Visibility: visibility,
+		Kind:       models.RoomKindChannel,
+	}
+
+	owner := []models.RoomMember{{UserID: ownerID, Role: models.RoomRoleOwner}}
+	if err := s.roomRepo.CreateWithMembers(room, owner); err != nil {
+		// Another room may have taken the slug since it was checked.
+		if _, findErr := s.roomRepo.FindBySlug(slug); findErr == nil {
+			return nil, ErrSlugTaken
+		}
+		return nil, errors.New("failed to create room")
+	}
+
+	roomResp := s.toRoomResponse(room)
+	return &roomResp, nil
+}
+
+func (s *RoomService) ListRooms(userID uint, limit, offset int) ([]models.RoomResponse, error) {
+	rooms, err := s.roomRepo.ListVisible(userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.RoomResponse, 0, len(rooms))
+	for i := range rooms {
+		response = append(response, s.toRoomResponse(&rooms[i]))
+	}
+
+	return response, nil
+}
+
+func (s *RoomService) GetRoom(slug string, userID uint) (*models.RoomResponse, error) {
+	room, err := s.FindRoom(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomResp := s.toRoomResponse(room)
+	return &roomResp, nil
+}
+
+func (s *RoomService) UpdateRoom(slug string, userID uint, req *UpdateRoomRequest) (*models.RoomResponse, error) {
+	room, member, err := s.RequireMember(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !member.CanModerate() {
+		return nil, ErrForbidden
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, ErrRoomArchived
+	}
+
+	if req.Name != nil {
+		room.Name = *req.Name
+	}
+	if req.Topic != nil {
+		room.Topic = *req.Topic
+	}
+	if req.Visibility != nil {
+		room.Visibility = *req.Visibility
+	}
+
+	if err := s.roomRepo.Update(room); err != nil {
+		return nil, errors.New("failed to update room")
+	}
+
+	roomResp := s.toRoomResponse(room)
+	return &roomResp, nil
+}
+
+func (s *RoomService) ArchiveRoom(slug string, userID uint) (*models.RoomResponse, error) {
+	room, member, err := s.RequireMember(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Role != models.RoomRoleOwner {
+		return nil, ErrForbidden
+	}
+
+	if room.ArchivedAt == nil {
+		now := time.Now()
+		room.ArchivedAt = &now
+		if err := s.roomRepo.Update(room); err != nil {
+			return nil, errors.New("failed to archive room")
+		}
+	}
+
+	roomResp := s.toRoomResponse(room)
+	return &roomResp, nil
+}
+
+func (s *RoomService) JoinRoom(slug string, userID uint) (*models.RoomMemberResponse, error) {
+	room, err := s.FindRoom(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member, err := s.memberRepo.Find(room.ID, userID); err == nil {
+		return s.toMemberResponse(member)
+	}
+
+	if room.Visibility != models.RoomVisibilityPublic || room.Kind == models.RoomKindDirect {
+		return nil, ErrForbidden
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, ErrRoomArchived
+	}
+
+	member := &models.RoomMember{RoomID: room.ID, UserID: userID, Role: models.RoomRoleMember}
+	if err := s.memberRepo.Create(member); err != nil {
+		return nil, errors.New("failed to join room")
+	}
+
+	return s.toMemberResponse(member)
+}
+
+func (s *RoomService) LeaveRoom(slug string, userID uint) error {
+	room, member, err := s.RequireMember(slug, userID)
+	if err != nil {
+		return err
+	}
+
+	// A direct room is reopened rather than rejoined, so its members stay.
+	if room.Kind == models.RoomKindDirect {
+		return ErrForbidden
+	}
+
+	if member.Role == models.RoomRoleOwner {
+		return ErrOwnerLeave
+	}
+
+	if err := s.memberRepo.Delete(member); err != nil {
+		return errors.New("failed to leave room")
+	}
+
+	return nil
+}
+
+func (s *RoomService) ListMembers(slug string, userID uint) ([]models.RoomMemberResponse, error) {
+	room, _, err := s.RequireMember(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.memberRepo.ListByRoom(room.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.RoomMemberResponse, 0, len(members))
+	for _, member := range members {
+		response = append(response, models.RoomMemberResponse{
+			User:              toUserResponse(&member.User),
+			Role:              member.Role,
+			JoinedAt:          member.CreatedAt,
+			LastReadMessageID: member.LastReadMessageID,
+			LastReadAt:        member.LastReadAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *RoomService) InviteMember(slug string, inviterID uint, req *InviteMemberRequest) (*models.RoomMemberResponse, error) {
+	room, inviter, err := s.RequireMember(slug, inviterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inviter.CanModerate() || room.Kind == models.RoomKindDirect {
+		return nil, ErrForbidden
+	}
+
+	if room.ArchivedAt != nil {
+		return nil, ErrRoomArchived
+	}
+
+	role := req.Role
+	if role == "" {
+		role = models.RoomRoleMember
+	}
+
+	// Only the owner may hand out moderation rights.
+	if role == models.RoomRoleAdmin && inviter.Role != models.RoomRoleOwner {
+		return nil, ErrForbidden
+	}
+
+	if _, err := s.userRepo.FindByID(req.UserID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	member, err := s.memberRepo.Find(room.ID, req.UserID)
+	if err == nil {
+		// As with kicks, admins can change the role of members and guests
+		// but never of the owner or each other.
+		if member.Role == models.RoomRoleOwner || (member.CanModerate() && inviter.Role != models.RoomRoleOwner) {
+			return nil, ErrForbidden
+		}
+		member.Role = role
+		if err := s.memberRepo.Update(member); err != nil {
+			return nil, errors.New("failed to update member")
+		}
+		return s.toMemberResponse(member)
+	}
+
+	member = &models.RoomMember{RoomID: room.ID, UserID: req.UserID, Role: role}
+	if err := s.memberRepo.Create(member); err != nil {
+		return nil, errors.New("failed to add member")
+	}
+
+	return s.toMemberResponse(member)
+}
+
+func (s *RoomService) KickMember(slug string, moderatorID, userID uint) error {
+	room, moderator, err := s.RequireMember(slug, moderatorID)
+	if err != nil {
+		return err
+	}
+
+	if !moderator.CanModerate() || moderatorID == userID {
+		return ErrForbidden
+	}
+
+	member, err := s.memberRepo.Find(room.ID, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	// Admins can remove members and guests, but never the owner or each other.
+	if member.Role == models.RoomRoleOwner || (member.CanModerate() && moderator.Role != models.RoomRoleOwner) {
+		return ErrForbidden
+	}
+
+	if err := s.memberRepo.Delete(member); err != nil {
+		return errors.New("failed to remove member")
+	}
+
+	return nil
+}
+
+// MarkRead advances the caller's read cursor in a room. It returns nil when
+// the cursor was already at or past messageID.
+func (s *RoomService) MarkRead(slug string, userID, messageID uint) (*models.RoomMember, error) {
+	room, member, err := s.RequireMember(slug, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	moved, err := s.memberRepo.AdvanceReadCursor(room.ID, userID, messageID, now)
+	if err != nil {
+		return nil, errors.New("failed to update read cursor")
+	}
+	if !moved {
+		return nil, nil
+	}
+
+	member.LastReadMessageID = messageID
+	member.LastReadAt = &now
+	return member, nil
+}
+
+// OpenDirectRoom returns the direct room shared by two users, creating it on
+// first use. The slug is derived from the sorted user IDs so both sides
+// always resolve to the same room.
+func (s *RoomService) OpenDirectRoom(userID, otherID uint) (*models.DirectConversationResponse, error) {
+	if userID == otherID {
+		return nil, ErrSelfDirect
+	}
+
+	other, err := s.userRepo.FindByID(otherID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	slug := directSlug(userID, otherID)
+	room, err := s.roomRepo.FindBySlug(slug)
+	if err != nil {
+		room, err = s.createDirectRoom(slug, userID, otherID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.DirectConversationResponse{
+		Room:        s.toRoomResponse(room),
+		Participant: toUserResponse(other),
+	}, nil
+}
+
+func (s *RoomService) ListDirectRooms(userID uint) ([]models.DirectConversationResponse, error) {
+	rooms, err := s.roomRepo.ListDirectForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.DirectConversationResponse, 0, len(rooms))
+	if len(rooms) == 0 {
+		return response, nil
+	}
+
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	others, err := s.memberRepo.ListOthersInRooms(roomIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make(map[uint]models.User, len(others))
+	for _, member := range others {
+		participants[member.RoomID] = member.User
+	}
+
+	for i := range rooms {
+		participant, ok := participants[rooms[i].ID]
+		if !ok {
+			continue
+		}
+		response = append(response, models.DirectConversationResponse{
+			Room:        s.toRoomResponse(&rooms[i]),
+			Participant: toUserResponse(&participant),
+		})
+	}
+
+	return response, nil
+}
+
+func (s *RoomService) createDirectRoom(slug string, userID, otherID uint) (*models.Room, error) {
+	room := &models.Room{
+		Name:       slug,
+		Slug:       slug,
+		OwnerID:    userID,
+		Visibility: models.RoomVisibilityPrivate,
+		Kind:       models.RoomKindDirect,
+	}
+
+	members := []models.RoomMember{
+		{UserID: userID, Role: models.RoomRoleMember},
+		{UserID: otherID, Role: models.RoomRoleMember},
+	}
+	if err := s.roomRepo.CreateWithMembers(room, members); err != nil {
+		// Both users may open the conversation at the same time. The loser's
+		// insert only fails on the unique slug once the winner's transaction
+		// has committed, so the room it reads back already has both members.
+		if existing, findErr := s.roomRepo.FindBySlug(slug); findErr == nil {
+			return existing, nil
+		}
+		return nil, errors.New("failed to create direct conversation")
+	}
+
+	return room, nil
+}
+
+// FindRoom resolves a room by slug, hiding private rooms the user cannot see.
+func (s *RoomService) FindRoom(slug string, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindBySlug(slug)
+	if err != nil {
+		return nil, ErrRoomNotFound
+	}
+
+	if room.Visibility == models.RoomVisibilityPrivate {
+		if _, err := s.memberRepo.Find(room.ID, userID); err != nil {
+			return nil, ErrRoomNotFound
+		}
+	}
+
+	return room, nil
+}
+
+// RequireMember resolves a room by slug and the caller's membership in it.
+func (s *RoomService) RequireMember(slug string, userID uint) (*models.Room, *models.RoomMember, error) {
+	room, err := s.FindRoom(slug, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	member, err := s.memberRepo.Find(room.ID, userID)
+	if err != nil {
+		return nil, nil, ErrNotMember
+	}
+
+	return room, member, nil
+}
+
+func (s *RoomService) toMemberResponse(member *models.RoomMember) (*models.RoomMemberResponse, error) {
+	user, err := s.userRepo.FindByID(member.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	return &models.RoomMemberResponse{
+		User:              toUserResponse(user),
+		Role:              member.Role,
+		JoinedAt:          member.CreatedAt,
+		LastReadMessageID: member.LastReadMessageID,
+		LastReadAt:        member.LastReadAt,
+	}, nil
+}
+
+func (s *RoomService) toRoomResponse(room *models.Room) models.RoomResponse {
+	return models.RoomResponse{
+		ID:         room.ID,
+		Name:       room.Name,
+		Slug:       room.Slug,
+		Topic:      room.Topic,
+		OwnerID:    room.OwnerID,
+		Visibility: room.Visibility,
+		Kind:       room.Kind,
+		ArchivedAt: room.ArchivedAt,
+		CreatedAt:  room.CreatedAt,
+	}
+}
+
+func slugify(name string) string {
+	slug := slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > 100 {
+		slug = strings.TrimRight(slug[:100], "-")
+	}
+	return slug
+}
+
+func directSlug(a, b uint) string {
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("%s%d-%d", directSlugPrefix, a, b)
+}
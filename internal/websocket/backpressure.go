@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SlowConsumerPolicy decides what happens when a client's Send buffer is full.
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDisconnect closes the connection with code 1008. The read
+	// pump then unregisters the client, so presence and user_left are
+	// handled exactly as for any other disconnect.
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+	// SlowConsumerDropOldest discards the oldest queued frame to make room.
+	// Clients can detect the gap from the room sequence numbers and resume.
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop-oldest"
+)
+
+func ParseSlowConsumerPolicy(s string) (SlowConsumerPolicy, error) {
+	switch policy := SlowConsumerPolicy(s); policy {
+	case "":
+		return SlowConsumerDisconnect, nil
+	case SlowConsumerDisconnect, SlowConsumerDropOldest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown slow consumer policy %q", s)
+	}
+}
+
+var (
+	framesDropped       = expvar.NewInt("ws_frames_dropped")
+	slowConsumersClosed = expvar.NewInt("ws_slow_consumers_disconnected")
+)
+
+// Metrics returns the slow-consumer counters served under /debug/vars. Only
+// these are served; the rest of expvar, such as cmdline and memstats, stays
+// private.
+func Metrics() map[string]int64 {
+	return map[string]int64{
+		"ws_frames_dropped":              framesDropped.Value(),
+		"ws_slow_consumers_disconnected": slowConsumersClosed.Value(),
+	}
+}
+
+// trySend queues a frame without blocking and applies the hub's slow
+// consumer policy when the buffer is full. Callers must guarantee the
+// client is still registered, so Send cannot be closed underneath them.
+func (c *Client) trySend(payload []byte) {
+	select {
+	case c.Send <- payload:
+		return
+	default:
+	}
+
+	if c.Hub.opts.SlowConsumer == SlowConsumerDropOldest {
+		select {
+		case <-c.Send:
+			framesDropped.Add(1)
+		default:
+		}
+		select {
+		case c.Send <- payload:
+		default:
+			framesDropped.Add(1)
+		}
+		return
+	}
+
+	framesDropped.Add(1)
+	c.evictOnce.Do(func() {
+		slowConsumersClosed.Add(1)
+		// A slow consumer's write pump is usually blocked mid-write, so the
+		// close frame can wait up to writeWait for the write lock. Callers
+		// hold hub and shard locks; never make them wait with it.
+		go func() {
+			c.Conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "slow consumer"),
+				time.Now().Add(writeWait))
+			c.Conn.Close()
+		}()
+	})
+}
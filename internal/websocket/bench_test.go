@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	benchClients = 10000
+	benchRooms   = 100
+	// benchInFlight bounds the messages queued ahead of delivery, so the
+	// benchmark measures fan-out rather than server_busy rejections.
+	benchInFlight = 256
+)
+
+// BenchmarkHubFanOut sends messages round-robin to 100 rooms of 100 clients
+// each, 10k clients in all, and reports how many frames reach clients per
+// second. Each message goes through the worker pool, the broker and the
+// room's shard, as it would from a socket.
+func BenchmarkHubFanOut(b *testing.B) {
+	for _, shards := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkHubFanOut(b, shards)
+		})
+	}
+}
+
+func benchmarkHubFanOut(b *testing.B, shards int) {
+	// The hub logs every connect and disconnect.
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	hub := NewHub(&testHandler{}, newTestPresence(), (&testBus{}).node(), Options{
+		Shards:       shards,
+		Workers:      32,
+		QueueSize:    256,
+		SlowConsumer: SlowConsumerDropOldest,
+	})
+	go hub.Run()
+
+	var delivered atomic.Int64
+	senders := make([]*Client, benchRooms)
+	clients := make([]*Client, benchClients)
+	for i := range clients {
+		roomID := fmt.Sprintf("room-%d", i%benchRooms)
+		client := connectTestClient(b, hub, uint(i+1), roomID)
+		clients[i] = client
+		if i < benchRooms {
+			senders[i] = client
+		}
+
+		go func() {
+			for payload := range client.Send {
+				if bytes.Contains(payload, []byte(`"type":"text"`)) {
+					delivered.Add(1)
+				}
+			}
+		}()
+	}
+	b.Cleanup(func() {
+		for _, client := range clients {
+			hub.Unregister <- client
+		}
+	})
+
+	const perMessage = benchClients / benchRooms
+	droppedBefore := framesDropped.Value()
+	done := func() int64 {
+		return delivered.Load() + framesDropped.Value() - droppedBefore
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for int64(i)-done()/perMessage > benchInFlight {
+			runtime.Gosched()
+		}
+
+		sender := senders[i%benchRooms]
+		hub.Broadcast <- &IncomingMessage{
+			Type:    "text",
+			Content: "benchmark",
+			RoomID:  fmt.Sprintf("room-%d", i%benchRooms),
+			UserID:  sender.UserID,
+			Client:  sender,
+		}
+	}
+
+	expected := int64(b.N) * perMessage
+	deadline := time.Now().Add(time.Minute)
+	for done() < expected {
+		if time.Now().After(deadline) {
+			b.Fatalf("%d of %d frames delivered", done(), expected)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(expected)/b.Elapsed().Seconds(), "frames/s")
+	b.ReportMetric(float64(framesDropped.Value()-droppedBefore), "dropped")
+}
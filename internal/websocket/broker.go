@@ -0,0 +1,14 @@
+package websocket
+
+// Broker carries room events between hubs. The hub publishes every room event
+// to the broker and only delivers what the broker hands back for the rooms it
+// has subscribed to, so single-node and multi-node deployments share one
+// delivery path.
+type Broker interface {
+	Publish(roomID string, payload []byte) error
+	// Subscribe registers deliver for a room; implementations must not hold
+	// internal locks while calling it.
+	Subscribe(roomID string, deliver func(payload []byte)) error
+	Unsubscribe(roomID string) error
+	Close() error
+}
@@ -3,6 +3,7 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -13,6 +14,15 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512
+	// maxFrameSize is the hard limit at which the connection is dropped;
+	// frames between maxMessageSize and maxFrameSize are rejected with a
+	// too_large error instead.
+	maxFrameSize = 16 * 1024
+
+	// Each client may send sendRate frames per second, in bursts of up to
+	// sendBurst.
+	sendRate  = 10
+	sendBurst = 20
 )
 
 type Client struct {
@@ -21,7 +31,141 @@ type Client struct {
 	Send     chan []byte
 	UserID   uint
 	Username string
-	RoomID   string
+	// SessionID is the login session the connection was authenticated
+	// with; revoking the session closes the connection.
+	SessionID string
+
+	mu    sync.Mutex
+	rooms map[string]*roomState
+
+	limiter   *rateLimiter
+	evictOnce sync.Once
+}
+
+// roomState tracks delivery for one subscription. While a resuming client
+// is being replayed from the database, live events are buffered and then
+// flushed, skipping anything the replay already covered. Live events past
+// replayedThrough are always delivered: across nodes they can arrive out of
+// order, and dropping the late one would lose it for good.
+type roomState struct {
+	replayedThrough int64
+	replaying       bool
+	buffered        []bufferedEvent
+}
+
+type bufferedEvent struct {
+	seq     int64
+	payload []byte
+}
+
+func NewClient(conn *websocket.Conn, userID uint, username, sessionID string) *Client {
+	return &Client{
+		Conn:      conn,
+		Send:      make(chan []byte, 256),
+		UserID:    userID,
+		Username:  username,
+		SessionID: sessionID,
+		rooms:     make(map[string]*roomState),
+		limiter:   newRateLimiter(sendRate, sendBurst),
+	}
+}
+
+// addRoom records a subscription and reports false if it already existed.
+// A positive sinceSeq puts the subscription into replay mode.
+func (c *Client) addRoom(roomID string, sinceSeq int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.rooms[roomID]; ok {
+		return false
+	}
+	c.rooms[roomID] = &roomState{replayedThrough: sinceSeq, replaying: sinceSeq > 0}
+	return true
+}
+
+// removeRoom drops a subscription and reports whether it existed.
+func (c *Client) removeRoom(roomID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.rooms[roomID]; !ok {
+		return false
+	}
+	delete(c.rooms, roomID)
+	return true
+}
+
+// takeRooms clears and returns all of the client's subscriptions.
+func (c *Client) takeRooms() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rooms := make([]string, 0, len(c.rooms))
+	for roomID := range c.rooms {
+		rooms = append(rooms, roomID)
+	}
+	c.rooms = make(map[string]*roomState)
+	return rooms
+}
+
+// deliver queues a live room event. Events the replay already covered are
+// dropped; during a replay they are held back until it finishes.
+// Callers must guarantee the client is still registered.
+func (c *Client) deliver(roomID string, seq int64, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.rooms[roomID]
+	if !ok {
+		return
+	}
+
+	if state.replaying {
+		state.buffered = append(state.buffered, bufferedEvent{seq: seq, payload: payload})
+		return
+	}
+
+	c.sendLive(state, seq, payload)
+}
+
+// replayed queues an event read back from the database during a replay.
+func (c *Client) replayed(roomID string, seq int64, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.rooms[roomID]
+	if !ok || seq <= state.replayedThrough {
+		return
+	}
+	state.replayedThrough = seq
+	c.trySend(payload)
+}
+
+// finishReplay flushes the events buffered during a replay and switches the
+// subscription to live delivery.
+func (c *Client) finishReplay(roomID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.rooms[roomID]
+	if !ok || !state.replaying {
+		return
+	}
+
+	for _, event := range state.buffered {
+		c.sendLive(state, event.seq, event.payload)
+	}
+	state.buffered = nil
+	state.replaying = false
+}
+
+// sendLive must be called with c.mu held.
+func (c *Client) sendLive(state *roomState, seq int64, payload []byte) {
+	if seq > 0 && seq <= state.replayedThrough {
+		return
+	}
+
+	c.trySend(payload)
 }
 
 func (c *Client) ReadPump() {
@@ -31,7 +175,7 @@ func (c *Client) ReadPump() {
 	}()
 
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.Conn.SetReadLimit(maxMessageSize)
+	c.Conn.SetReadLimit(maxFrameSize)
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
@@ -46,18 +190,58 @@ func (c *Client) ReadPump() {
 			break
 		}
 
+		if len(message) > maxMessageSize {
+			c.sendError(ErrorCodeTooLarge, "message exceeds 512 bytes", nil)
+			continue
+		}
+
 		var incomingMsg IncomingMessage
 		if err := json.Unmarshal(message, &incomingMsg); err != nil {
 			log.Printf("Error parsing message: %v", err)
+			c.sendError(ErrorCodeInvalidJSON, "message is not valid JSON", nil)
+			continue
+		}
+
+		if !c.limiter.allow() {
+			c.sendError(ErrorCodeRateLimited, "too many messages, slow down", &incomingMsg)
+			continue
+		}
+
+		if incomingMsg.Type == "presence" {
+			c.Hub.Presence <- &PresenceUpdate{Client: c, Status: incomingMsg.Content}
 			continue
 		}
 
-		incomingMsg.UserID = c.UserID
-		incomingMsg.Username = c.Username
-		incomingMsg.RoomID = c.RoomID
+		if incomingMsg.RoomID == "" {
+			log.Printf("Message without room_id from %s", c.Username)
+			c.sendError(ErrorCodeInvalidRequest, "room_id required", &incomingMsg)
+			continue
+		}
+
+		switch incomingMsg.Type {
+		case "subscribe":
+			c.Hub.Subscribe <- &Subscription{Client: c, RoomID: incomingMsg.RoomID, SinceSeq: incomingMsg.SinceSeq}
+		case "unsubscribe":
+			c.Hub.Unsubscribe <- &Subscription{Client: c, RoomID: incomingMsg.RoomID}
+		default:
+			incomingMsg.UserID = c.UserID
+			incomingMsg.Username = c.Username
+			incomingMsg.Client = c
+
+			c.Hub.Broadcast <- &incomingMsg
+		}
+	}
+}
 
-		c.Hub.Broadcast <- &incomingMsg
+// sendError reports a rejected frame back to this client. msg, when the
+// frame could be parsed, supplies the room and client_msg_id it referred to.
+func (c *Client) sendError(code, message string, msg *IncomingMessage) {
+	frame := &ErrorFrame{Type: "error", Code: code, Message: message}
+	if msg != nil {
+		frame.RoomID = msg.RoomID
+		frame.ClientMsgID = msg.ClientMsgID
 	}
+	c.Hub.sendError(c, frame)
 }
 
 func (c *Client) WritePump() {
@@ -82,10 +266,21 @@ func (c *Client) WritePump() {
 			}
 			w.Write(message)
 
+			// Under the drop-oldest policy frames can be taken out of Send
+			// concurrently, so never block on the queued count.
 			n := len(c.Send)
+		drain:
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.Send)
+				select {
+				case queued, ok := <-c.Send:
+					if !ok {
+						break drain
+					}
+					w.Write([]byte{'\n'})
+					w.Write(queued)
+				default:
+					break drain
+				}
 			}
 
 			if err := w.Close(); err != nil {
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"strconv"
+	"testing"
+)
+
+// sent drains and returns the payloads queued for a client.
+func sent(client *Client) []string {
+	var payloads []string
+	for {
+		select {
+		case payload := <-client.Send:
+			payloads = append(payloads, string(payload))
+		default:
+			return payloads
+		}
+	}
+}
+
+func seqPayload(seq int64) []byte {
+	return []byte(strconv.FormatInt(seq, 10))
+}
+
+func TestClientDeliversLiveEventsOutOfOrder(t *testing.T) {
+	client := NewClient(nil, 1, "", "")
+	client.addRoom("general", 0)
+
+	// Events published by different nodes can overtake each other.
+	client.deliver("general", 2, seqPayload(2))
+	client.deliver("general", 1, seqPayload(1))
+
+	if got := sent(client); len(got) != 2 || got[0] != "2" || got[1] != "1" {
+		t.Fatalf("sent %v, want [2 1]", got)
+	}
+}
+
+func TestClientSkipsEventsCoveredByReplay(t *testing.T) {
+	client := NewClient(nil, 1, "", "")
+	client.addRoom("general", 5)
+
+	client.deliver("general", 7, seqPayload(7))
+	client.replayed("general", 6, seqPayload(6))
+	client.replayed("general", 7, seqPayload(7))
+	client.finishReplay("general")
+
+	// Already replayed, only late to arrive live.
+	client.deliver("general", 6, seqPayload(6))
+	client.deliver("general", 9, seqPayload(9))
+	client.deliver("general", 8, seqPayload(8))
+
+	want := []string{"6", "7", "9", "8"}
+	got := sent(client)
+	if len(got) != len(want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sent %v, want %v", got, want)
+		}
+	}
+}
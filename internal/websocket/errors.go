@@ -0,0 +1,61 @@
+package websocket
+
+import "errors"
+
+// Error codes carried by error frames. Clients can switch on the code; the
+// message is meant for people.
+const (
+	ErrorCodeInvalidJSON    = "invalid_json"
+	ErrorCodeInvalidRequest = "invalid_request"
+	ErrorCodeTooLarge       = "too_large"
+	ErrorCodeForbidden      = "forbidden"
+	ErrorCodeNotFound       = "not_found"
+	ErrorCodeRateLimited    = "rate_limited"
+	ErrorCodePersistFailed  = "persist_failed"
+	ErrorCodeServerBusy     = "server_busy"
+	ErrorCodeInternal       = "internal_error"
+)
+
+// ErrorFrame is sent only to the client whose frame caused the error.
+type ErrorFrame struct {
+	Type        string `json:"type"`
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	RoomID      string `json:"room_id,omitempty"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+}
+
+// Error attaches an error code to an error returned by a MessageHandler.
+// Errors without one are reported as persist_failed.
+type Error struct {
+	Code string
+	Err  error
+}
+
+func NewError(code string, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newErrorFrame(err error, roomID, clientMsgID string) *ErrorFrame {
+	code := ErrorCodePersistFailed
+	var coded *Error
+	if errors.As(err, &coded) {
+		code = coded.Code
+	}
+
+	return &ErrorFrame{
+		Type:        "error",
+		Code:        code,
+		Message:     err.Error(),
+		RoomID:      roomID,
+		ClientMsgID: clientMsgID,
+	}
+}
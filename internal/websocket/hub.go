@@ -2,121 +2,466 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
-	rooms          map[string]map[*Client]bool
-	Broadcast      chan *IncomingMessage
-	Register       chan *Client
-	Unregister     chan *Client
-	mu             sync.RWMutex
+	Broadcast   chan *IncomingMessage
+	Register    chan *Client
+	Unregister  chan *Client
+	Subscribe   chan *Subscription
+	Unsubscribe chan *Subscription
+	Presence    chan *PresenceUpdate
+
+	mu      sync.RWMutex
+	clients map[*Client]bool
+	// presence is only touched by Run, nodes only written by the presence
+	// queue. nodes holds, for each user connected anywhere, the nodes they
+	// are connected to and how many connections each has per session.
+	nodeID   string
+	presence map[uint]*userPresence
+	nodesMu  sync.RWMutex
+	nodes    map[uint]map[string]map[string]int
+
+	shards        []*shard
+	workers       *workerPool
+	presenceQueue *presenceQueue
+
 	messageHandler MessageHandler
+	presenceStore  PresenceStore
+	broker         Broker
+	receipts       *receiptDebouncer
+	opts           Options
 }
 
+type Options struct {
+	// Shards is the number of room partitions, each with its own fan-out
+	// goroutine.
+	Shards int
+	// Workers is the number of goroutines persisting messages and
+	// authorizing subscriptions; QueueSize bounds each worker's backlog.
+	Workers   int
+	QueueSize int
+	// SlowConsumer is applied when a client's Send buffer is full.
+	SlowConsumer SlowConsumerPolicy
+}
+
+// replayLimit caps how many missed events are replayed on resume; it stays
+// below the Send buffer so a replay cannot overflow it by itself.
+const replayLimit = 200
+
 type MessageHandler interface {
 	HandleMessage(msg *IncomingMessage) (*OutgoingMessage, error)
+	AuthorizeRoom(roomID string, userID uint) error
+	MessagesSince(roomID string, sinceSeq int64, limit int) ([]*OutgoingMessage, error)
+}
+
+type Subscription struct {
+	Client *Client
+	RoomID string
+	// SinceSeq, when positive, replays the room's events after that
+	// sequence number before switching to live delivery.
+	SinceSeq int64
 }
 
-func NewHub(messageHandler MessageHandler) *Hub {
-	return &Hub{
-		rooms:          make(map[string]map[*Client]bool),
+func NewHub(messageHandler MessageHandler, presenceStore PresenceStore, broker Broker, opts Options) *Hub {
+	h := &Hub{
 		Broadcast:      make(chan *IncomingMessage),
 		Register:       make(chan *Client),
 		Unregister:     make(chan *Client),
+		Subscribe:      make(chan *Subscription),
+		Unsubscribe:    make(chan *Subscription),
+		Presence:       make(chan *PresenceUpdate),
+		clients:        make(map[*Client]bool),
+		nodeID:         newNodeID(),
+		presence:       make(map[uint]*userPresence),
+		nodes:          make(map[uint]map[string]map[string]int),
+		shards:         make([]*shard, opts.Shards),
+		workers:        newWorkerPool(opts.Workers, opts.QueueSize),
+		presenceQueue:  newPresenceQueue(),
 		messageHandler: messageHandler,
+		presenceStore:  presenceStore,
+		broker:         broker,
+		opts:           opts,
 	}
+	for i := range h.shards {
+		h.shards[i] = newShard(h)
+	}
+	h.receipts = newReceiptDebouncer(h.BroadcastEvent)
+	return h
 }
 
+// Run owns the connection registry and presence counts and routes room work
+// to the worker pool. It never blocks on the database or on slow clients.
 func (h *Hub) Run() {
+	for _, s := range h.shards {
+		go s.run()
+	}
+	h.workers.start()
+	go h.presenceQueue.run()
+	h.presenceQueue.push(h.joinCluster)
+
 	for {
 		select {
 		case client := <-h.Register:
 			client.Hub = h
 
 			h.mu.Lock()
-			if h.rooms[client.RoomID] == nil {
-				h.rooms[client.RoomID] = make(map[*Client]bool)
-			}
-			h.rooms[client.RoomID][client] = true
+			h.clients[client] = true
 			h.mu.Unlock()
 
-			log.Printf("✅ Client joined: %s (room: %s, total: %d)",
-				client.Username, client.RoomID, len(h.rooms[client.RoomID]))
+			log.Printf("✅ Client connected: %s", client.Username)
 
-			h.broadcastToRoom(client.RoomID, &OutgoingMessage{
-				Type:      "user_joined",
-				Content:   client.Username + " joined the chat",
-				UserID:    client.UserID,
-				Username:  client.Username,
-				RoomID:    client.RoomID,
-				CreatedAt: time.Now(),
-			}, nil)
+			h.connect(client)
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if clients, ok := h.rooms[client.RoomID]; ok {
-				if _, exists := clients[client]; exists {
-					delete(clients, client)
-					close(client.Send)
-
-					if len(clients) == 0 {
-						delete(h.rooms, client.RoomID)
-					}
-				}
+			if _, ok := h.clients[client]; !ok {
+				h.mu.Unlock()
+				continue
+			}
+			delete(h.clients, client)
+
+			left := client.takeRooms()
+			for _, roomID := range left {
+				h.shardFor(roomID).remove(client, roomID)
 			}
+			close(client.Send)
 			h.mu.Unlock()
 
-			log.Printf("❌ Client left: %s (room: %s)", client.Username, client.RoomID)
+			log.Printf("❌ Client disconnected: %s", client.Username)
 
-			h.broadcastToRoom(client.RoomID, &OutgoingMessage{
-				Type:      "user_left",
-				Content:   client.Username + " left the chat",
-				UserID:    client.UserID,
-				Username:  client.Username,
-				RoomID:    client.RoomID,
-				CreatedAt: time.Now(),
-			}, nil)
+			// Releasing broker subscriptions may wait on the network, which
+			// Run must not do; they are released on their own goroutine.
+			go func() {
+				for _, roomID := range left {
+					h.shardFor(roomID).release(roomID)
+				}
+			}()
 
-		case message := <-h.Broadcast:
-			outgoingMsg, err := h.messageHandler.HandleMessage(message)
-			if err != nil {
-				log.Printf("❌ Error handling message: %v", err)
-				continue
+			for _, roomID := range left {
+				roomID := roomID
+				h.workers.submit(roomID, func() {
+					h.broadcastToRoom(roomID, leftMessage(client, roomID))
+				})
 			}
 
-			h.broadcastToRoom(message.RoomID, outgoingMsg, nil)
+			h.disconnect(client)
+
+		case sub := <-h.Subscribe:
+			if !h.workers.submit(sub.RoomID, func() { h.subscribe(sub) }) {
+				h.sendError(sub.Client, &ErrorFrame{
+					Type:    "error",
+					Code:    ErrorCodeServerBusy,
+					Message: "server busy",
+					RoomID:  sub.RoomID,
+				})
+			}
+
+		case sub := <-h.Unsubscribe:
+			h.workers.submit(sub.RoomID, func() { h.unsubscribe(sub.Client, sub.RoomID) })
+
+		case update := <-h.Presence:
+			h.updatePresence(update)
+
+		case message := <-h.Broadcast:
+			if !h.workers.submit(message.RoomID, func() { h.handleMessage(message) }) {
+				h.sendError(message.Client, &ErrorFrame{
+					Type:        "error",
+					Code:        ErrorCodeServerBusy,
+					Message:     "server busy",
+					RoomID:      message.RoomID,
+					ClientMsgID: message.ClientMsgID,
+				})
+			}
 		}
 	}
 }
 
-func (h *Hub) broadcastToRoom(roomID string, message *OutgoingMessage, excludeClient *Client) {
+func (h *Hub) handleMessage(message *IncomingMessage) {
+	outgoingMsg, err := h.messageHandler.HandleMessage(message)
+	if err != nil && !errors.Is(err, ErrDuplicateMessage) {
+		log.Printf("❌ Error handling message: %v", err)
+		h.sendError(message.Client, newErrorFrame(err, message.RoomID, message.ClientMsgID))
+		return
+	}
+	if outgoingMsg == nil {
+		return
+	}
+
+	if message.ClientMsgID != "" {
+		h.sendToClient(message.Client, &OutgoingMessage{
+			ID:          outgoingMsg.ID,
+			Type:        "ack",
+			RoomID:      outgoingMsg.RoomID,
+			Seq:         outgoingMsg.Seq,
+			ClientMsgID: message.ClientMsgID,
+			CreatedAt:   outgoingMsg.CreatedAt,
+		})
+	}
+
+	// A retry of a message that was already stored has already been
+	// broadcast; the sender only needed the ack.
+	if err != nil {
+		return
+	}
+
+	if outgoingMsg.Type == "read_receipt" {
+		h.receipts.add(outgoingMsg)
+		return
+	}
+
+	h.broadcastToRoom(outgoingMsg.RoomID, outgoingMsg)
+}
+
+// BroadcastEvent delivers a server-generated event, such as an edit made over
+// REST, to everyone subscribed to its room.
+func (h *Hub) BroadcastEvent(message *OutgoingMessage) {
+	h.broadcastToRoom(message.RoomID, message)
+}
+
+// BroadcastReceipt queues a read receipt for debounced delivery.
+func (h *Hub) BroadcastReceipt(receipt *OutgoingMessage) {
+	h.receipts.add(receipt)
+}
+
+// RemoveUser drops every live subscription a user holds in a room, e.g.
+// after they have been kicked or have left it. The member_removed event goes
+// through the broker, so the user's connections on every node are dropped.
+func (h *Hub) RemoveUser(roomID string, userID uint) {
+	h.broadcastToRoom(roomID, &OutgoingMessage{
+		Type:      "member_removed",
+		RoomID:    roomID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CloseSession disconnects every connection opened with a session, e.g.
+// after it was logged out. The clients are then unregistered as usual.
+func (h *Hub) CloseSession(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+
+	var closing []*Client
 	h.mu.RLock()
-	clients := h.rooms[roomID]
+	for client := range h.clients {
+		if client.SessionID == sessionID {
+			closing = append(closing, client)
+		}
+	}
 	h.mu.RUnlock()
 
-	if clients == nil {
+	for _, client := range closing {
+		client.Conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session revoked"),
+			time.Now().Add(writeWait))
+		client.Conn.Close()
+	}
+}
+
+// SessionConnections counts a user's open connections per session across
+// the cluster, as last announced by each node.
+func (h *Hub) SessionConnections(userID uint) map[string]int {
+	h.nodesMu.RLock()
+	defer h.nodesMu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, sessions := range h.nodes[userID] {
+		for sessionID, n := range sessions {
+			counts[sessionID] += n
+		}
+	}
+	return counts
+}
+
+func (h *Hub) subscribe(sub *Subscription) {
+	client, roomID := sub.Client, sub.RoomID
+
+	if err := h.messageHandler.AuthorizeRoom(roomID, client.UserID); err != nil {
+		h.sendError(client, newErrorFrame(err, roomID, ""))
 		return
 	}
 
-	messageJSON, err := json.Marshal(message)
+	// Subscribe to the broker before taking the hub lock, so a slow broker
+	// never holds up Run.
+	shard := h.shardFor(roomID)
+	if err := shard.acquire(roomID); err != nil {
+		log.Printf("❌ Error subscribing to broker: %v", err)
+		h.sendError(client, &ErrorFrame{
+			Type:    "error",
+			Code:    ErrorCodeInternal,
+			Message: "failed to subscribe",
+			RoomID:  roomID,
+		})
+		return
+	}
+
+	// Holding the read lock keeps Unregister from closing the client while
+	// it is being added.
+	h.mu.RLock()
+	if _, ok := h.clients[client]; !ok || !client.addRoom(roomID, sub.SinceSeq) {
+		h.mu.RUnlock()
+		shard.release(roomID)
+		return
+	}
+	total := shard.add(client, roomID)
+	h.mu.RUnlock()
+
+	log.Printf("✅ Client joined: %s (room: %s, total: %d)", client.Username, roomID, total)
+
+	h.sendToClient(client, &OutgoingMessage{
+		Type:      "subscribed",
+		RoomID:    roomID,
+		CreatedAt: time.Now(),
+	})
+
+	if sub.SinceSeq > 0 {
+		h.replay(client, roomID, sub.SinceSeq)
+	}
+
+	h.broadcastToRoom(roomID, &OutgoingMessage{
+		Type:      "user_joined",
+		Content:   client.Username + " joined the chat",
+		UserID:    client.UserID,
+		Username:  client.Username,
+		RoomID:    roomID,
+		CreatedAt: time.Now(),
+	})
+}
+
+// replay sends a resuming client the events it missed. Live events that
+// arrive meanwhile are buffered by the client and deduplicated by sequence
+// number, so the client sees every event once.
+func (h *Hub) replay(client *Client, roomID string, sinceSeq int64) {
+	events, err := h.messageHandler.MessagesSince(roomID, sinceSeq, replayLimit)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		log.Printf("❌ Error replaying room %s: %v", roomID, err)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.clients[client]; !ok {
 		return
 	}
 
-	for client := range clients {
-		if excludeClient != nil && client == excludeClient {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
 			continue
 		}
+		client.replayed(roomID, event.Seq, payload)
+	}
 
-		select {
-		case client.Send <- messageJSON:
-		default:
-			close(client.Send)
-			delete(clients, client)
+	// Too far behind, or the replay failed: tell the client to reload
+	// history over REST rather than leave a silent gap.
+	if err != nil || len(events) == replayLimit {
+		if payload, err := json.Marshal(&OutgoingMessage{
+			Type:      "resync_required",
+			RoomID:    roomID,
+			CreatedAt: time.Now(),
+		}); err == nil {
+			client.trySend(payload)
 		}
 	}
+
+	client.finishReplay(roomID)
+}
+
+func (h *Hub) unsubscribe(client *Client, roomID string) {
+	if h.detach(client, roomID) {
+		h.unsubscribed(client, roomID)
+	}
+}
+
+// detach stops delivering a room to a client and reports whether the client
+// was subscribed to it. It only takes locks, so shards call it directly.
+func (h *Hub) detach(client *Client, roomID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.clients[client]; !ok || !client.removeRoom(roomID) {
+		return false
+	}
+	h.shardFor(roomID).remove(client, roomID)
+	return true
+}
+
+// unsubscribed releases a detached client's broker subscription and tells
+// the room it left.
+func (h *Hub) unsubscribed(client *Client, roomID string) {
+	h.shardFor(roomID).release(roomID)
+
+	log.Printf("❌ Client left: %s (room: %s)", client.Username, roomID)
+
+	h.sendToClient(client, &OutgoingMessage{
+		Type:      "unsubscribed",
+		RoomID:    roomID,
+		CreatedAt: time.Now(),
+	})
+
+	h.broadcastToRoom(roomID, leftMessage(client, roomID))
+}
+
+func (h *Hub) shardFor(roomID string) *shard {
+	return h.shards[partition(roomID, len(h.shards))]
+}
+
+func (h *Hub) sendToClient(client *Client, message *OutgoingMessage) {
+	h.send(client, message)
+}
+
+// sendError reports a failure to the client that caused it and nobody else.
+func (h *Hub) sendError(client *Client, frame *ErrorFrame) {
+	h.send(client, frame)
+}
+
+func (h *Hub) send(client *Client, message interface{}) {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+
+	client.trySend(messageJSON)
+}
+
+// broadcastToRoom publishes an event to every subscriber of a room on every
+// node; local clients receive it back through the broker.
+func (h *Hub) broadcastToRoom(roomID string, message *OutgoingMessage) {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	if err := h.broker.Publish(roomID, messageJSON); err != nil {
+		log.Printf("❌ Error publishing to broker: %v", err)
+	}
+}
+
+func leftMessage(client *Client, roomID string) *OutgoingMessage {
+	return &OutgoingMessage{
+		Type:      "user_left",
+		Content:   client.Username + " left the chat",
+		UserID:    client.UserID,
+		Username:  client.Username,
+		RoomID:    roomID,
+		CreatedAt: time.Now(),
+	}
 }
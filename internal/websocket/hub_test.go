@@ -0,0 +1,301 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testBus is a broker shared by several hubs in one process, standing in
+// for Redis or Postgres between nodes.
+type testBus struct {
+	mu    sync.Mutex
+	nodes []*testBroker
+}
+
+func (b *testBus) node() *testBroker {
+	node := &testBroker{bus: b, handlers: make(map[string]func([]byte))}
+	b.mu.Lock()
+	b.nodes = append(b.nodes, node)
+	b.mu.Unlock()
+	return node
+}
+
+type testBroker struct {
+	bus      *testBus
+	mu       sync.Mutex
+	handlers map[string]func([]byte)
+}
+
+func (b *testBroker) Publish(roomID string, payload []byte) error {
+	b.bus.mu.Lock()
+	nodes := append([]*testBroker(nil), b.bus.nodes...)
+	b.bus.mu.Unlock()
+
+	for _, node := range nodes {
+		node.mu.Lock()
+		deliver := node.handlers[roomID]
+		node.mu.Unlock()
+		if deliver != nil {
+			deliver(payload)
+		}
+	}
+	return nil
+}
+
+func (b *testBroker) Subscribe(roomID string, deliver func([]byte)) error {
+	b.mu.Lock()
+	b.handlers[roomID] = deliver
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *testBroker) subscribed(roomID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.handlers[roomID] != nil
+}
+
+func (b *testBroker) Unsubscribe(roomID string) error {
+	b.mu.Lock()
+	delete(b.handlers, roomID)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *testBroker) Close() error {
+	return nil
+}
+
+type testHandler struct {
+	seq atomic.Int64
+}
+
+func (h *testHandler) HandleMessage(msg *IncomingMessage) (*OutgoingMessage, error) {
+	return &OutgoingMessage{
+		Type:      "text",
+		Content:   msg.Content,
+		RoomID:    msg.RoomID,
+		Seq:       h.seq.Add(1),
+		UserID:    msg.UserID,
+		Username:  msg.Username,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (h *testHandler) AuthorizeRoom(roomID string, userID uint) error {
+	return nil
+}
+
+func (h *testHandler) MessagesSince(roomID string, sinceSeq int64, limit int) ([]*OutgoingMessage, error) {
+	return nil, nil
+}
+
+// testPresence records every status written, by user.
+type testPresence struct {
+	mu       sync.Mutex
+	statuses map[uint][]string
+	changed  chan struct{}
+}
+
+func newTestPresence() *testPresence {
+	return &testPresence{statuses: make(map[uint][]string), changed: make(chan struct{}, 64)}
+}
+
+func (p *testPresence) SetPresence(userID uint, status string) (string, []string, error) {
+	p.mu.Lock()
+	p.statuses[userID] = append(p.statuses[userID], status)
+	p.mu.Unlock()
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+	return "", nil, nil
+}
+
+func (p *testPresence) history(userID uint) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.statuses[userID]...)
+}
+
+// waitForStatus waits until the last status written for a user is status.
+func (p *testPresence) waitForStatus(t testing.TB, userID uint, status string) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		if history := p.history(userID); len(history) > 0 && history[len(history)-1] == status {
+			return
+		}
+		select {
+		case <-p.changed:
+		case <-timeout:
+			t.Fatalf("user %d never became %s; history %v", userID, status, p.history(userID))
+		}
+	}
+}
+
+func newTestHub(broker *testBroker, handler MessageHandler) *Hub {
+	return newTestHubWithPresence(broker, handler, newTestPresence())
+}
+
+func newTestHubWithPresence(broker *testBroker, handler MessageHandler, presence PresenceStore) *Hub {
+	hub := NewHub(handler, presence, broker, Options{
+		Shards:       4,
+		Workers:      4,
+		QueueSize:    1024,
+		SlowConsumer: SlowConsumerDropOldest,
+	})
+	go hub.Run()
+
+	// Wait until the hub has joined the cluster, so it hears about every
+	// connection the other hubs open from here on.
+	for !broker.subscribed(presenceRoom) {
+		time.Sleep(time.Millisecond)
+	}
+	return hub
+}
+
+func connectTestClient(t testing.TB, hub *Hub, userID uint, roomID string) *Client {
+	client := NewClient(nil, userID, "", "")
+	hub.Register <- client
+	hub.Subscribe <- &Subscription{Client: client, RoomID: roomID}
+	waitFor(t, client, "subscribed", "")
+	return client
+}
+
+// waitFor reads frames until one of the given type (and content, if set)
+// arrives.
+func waitFor(t testing.TB, client *Client, frameType, content string) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case payload := <-client.Send:
+			var frame OutgoingMessage
+			if err := json.Unmarshal(payload, &frame); err != nil {
+				t.Fatalf("invalid frame %s: %v", payload, err)
+			}
+			if frame.Type == frameType && (content == "" || frame.Content == content) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %q frame received", frameType)
+		}
+	}
+}
+
+func TestHubsShareRoomThroughBroker(t *testing.T) {
+	bus := &testBus{}
+	handler := &testHandler{}
+	hubA := newTestHub(bus.node(), handler)
+	hubB := newTestHub(bus.node(), handler)
+
+	sender := connectTestClient(t, hubA, 1, "general")
+	receiver := connectTestClient(t, hubB, 2, "general")
+
+	hubA.Broadcast <- &IncomingMessage{
+		Type:    "text",
+		Content: "hello from A",
+		RoomID:  "general",
+		UserID:  sender.UserID,
+		Client:  sender,
+	}
+
+	waitFor(t, receiver, "text", "hello from A")
+}
+
+func TestRemoveUserReachesOtherHubs(t *testing.T) {
+	bus := &testBus{}
+	handler := &testHandler{}
+	hubA := newTestHub(bus.node(), handler)
+	hubB := newTestHub(bus.node(), handler)
+
+	sender := connectTestClient(t, hubA, 1, "general")
+	kicked := connectTestClient(t, hubB, 2, "general")
+	bystander := connectTestClient(t, hubB, 3, "general")
+
+	hubA.RemoveUser("general", kicked.UserID)
+	waitFor(t, kicked, "member_removed", "")
+	waitFor(t, kicked, "unsubscribed", "")
+
+	hubA.Broadcast <- &IncomingMessage{
+		Type:    "text",
+		Content: "after the kick",
+		RoomID:  "general",
+		UserID:  sender.UserID,
+		Client:  sender,
+	}
+	// Hub B delivers in order, so once the bystander has the message the
+	// removed user would have had it too.
+	waitFor(t, bystander, "text", "after the kick")
+
+	for {
+		select {
+		case payload := <-kicked.Send:
+			var frame OutgoingMessage
+			json.Unmarshal(payload, &frame)
+			if frame.Type == "text" {
+				t.Fatalf("removed user received %s", payload)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestPresenceIsSharedAcrossHubs(t *testing.T) {
+	bus := &testBus{}
+	handler := &testHandler{}
+	presence := newTestPresence()
+	hubA := newTestHubWithPresence(bus.node(), handler, presence)
+	hubB := newTestHubWithPresence(bus.node(), handler, presence)
+
+	onA := connectTestClient(t, hubA, 1, "general")
+	presence.waitForStatus(t, 1, statusOnline)
+	onB := connectTestClient(t, hubB, 1, "general")
+
+	// Closing the user's socket on B must not mark them offline while the
+	// one on A is still open. B handles presence in order, so once another
+	// user is online there, the disconnect has been dealt with.
+	hubB.Unregister <- onB
+	connectTestClient(t, hubB, 2, "general")
+	presence.waitForStatus(t, 2, statusOnline)
+	if history := presence.history(1); len(history) != 1 {
+		t.Fatalf("user 1 presence history %v, want [online]", history)
+	}
+
+	hubA.Unregister <- onA
+	presence.waitForStatus(t, 1, statusOffline)
+}
+
+func TestSessionConnectionsAreSharedAcrossHubs(t *testing.T) {
+	bus := &testBus{}
+	handler := &testHandler{}
+	hubA := newTestHub(bus.node(), handler)
+	hubB := newTestHub(bus.node(), handler)
+
+	for _, conn := range []struct {
+		hub       *Hub
+		sessionID string
+	}{{hubA, "laptop"}, {hubB, "laptop"}, {hubB, "phone"}} {
+		conn.hub.Register <- NewClient(nil, 1, "", conn.sessionID)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		counts := hubA.SessionConnections(1)
+		if counts["laptop"] == 2 && counts["phone"] == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session connections %v, want laptop:2 phone:1", counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
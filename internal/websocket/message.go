@@ -1,22 +1,46 @@
 package websocket
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrDuplicateMessage is returned by MessageHandler.HandleMessage together
+// with the already stored event when a send is retried with a known
+// client_msg_id.
+var ErrDuplicateMessage = errors.New("duplicate message")
 
 type IncomingMessage struct {
-	Type     string `json:"type"`
-	Content  string `json:"content"`
-	RoomID   string `json:"room_id"`
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	Type        string `json:"type"`
+	Content     string `json:"content"`
+	RoomID      string `json:"room_id"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+	MessageID   uint   `json:"message_id,omitempty"`
+	ParentID    uint   `json:"parent_id,omitempty"`
+	Emoji       string `json:"emoji,omitempty"`
+	SinceSeq    int64  `json:"since_seq,omitempty"`
+	UserID      uint   `json:"user_id"`
+	Username    string `json:"username"`
+
+	// Client is the sender, used to ack the message or report its failure.
+	Client *Client `json:"-"`
 }
 
 type OutgoingMessage struct {
-	ID        uint      `json:"id,omitempty"`
-	Type      string    `json:"type"`
-	Content   string    `json:"content"`
-	RoomID    string    `json:"room_id"`
-	UserID    uint      `json:"user_id"`
-	Username  string    `json:"username"`
-	Avatar    string    `json:"avatar,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uint       `json:"id,omitempty"`
+	Type         string     `json:"type"`
+	Content      string     `json:"content"`
+	RoomID       string     `json:"room_id"`
+	Seq          int64      `json:"seq,omitempty"`
+	ClientMsgID  string     `json:"client_msg_id,omitempty"`
+	UserID       uint       `json:"user_id"`
+	Username     string     `json:"username"`
+	Avatar       string     `json:"avatar,omitempty"`
+	Emoji        string     `json:"emoji,omitempty"`
+	ParentID     *uint      `json:"parent_id,omitempty"`
+	ThreadRootID *uint      `json:"thread_root_id,omitempty"`
+	ReplyCount   int        `json:"reply_count,omitempty"`
+	LastReplyAt  *time.Time `json:"last_reply_at,omitempty"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
@@ -0,0 +1,296 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	statusOnline  = "online"
+	statusAway    = "away"
+	statusOffline = "offline"
+)
+
+// presenceRoom is the broker room on which hubs tell each other which users
+// they have connections for. Room slugs cannot contain a colon, so it never
+// collides with a real room.
+const presenceRoom = "hub:presence"
+
+const (
+	// presenceEventHello is published by a starting hub; the others answer
+	// with a presenceEventConnected for each user connected to them. A node
+	// also sends one whenever a user's connection count there changes.
+	presenceEventHello     = "hello"
+	presenceEventConnected = "connected"
+)
+
+type presenceEvent struct {
+	Type      string `json:"type"`
+	Node      string `json:"node"`
+	UserID    uint   `json:"user_id,omitempty"`
+	Connected bool   `json:"connected,omitempty"`
+
+	Sessions map[string]int `json:"sessions,omitempty"`
+}
+
+type PresenceStore interface {
+	// SetPresence persists a user's status and returns the user's stored
+	// username and the rooms whose members should be told about it.
+	SetPresence(userID uint, status string) (username string, roomIDs []string, err error)
+}
+
+type PresenceUpdate struct {
+	Client *Client
+	Status string
+}
+
+// userPresence counts a user's live connections on this node across all
+// rooms, in total and per session. Whether the user is online is decided
+// across the cluster: they are online while at least one node has a
+// connection open.
+type userPresence struct {
+	connections int
+	sessions    map[string]int
+	status      string
+}
+
+func newNodeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// connect and disconnect must only be called from Hub.Run.
+func (h *Hub) connect(client *Client) {
+	p, ok := h.presence[client.UserID]
+	if !ok {
+		p = &userPresence{sessions: make(map[string]int)}
+		h.presence[client.UserID] = p
+	}
+	p.connections++
+	p.sessions[client.SessionID]++
+
+	if p.connections == 1 {
+		p.status = statusOnline
+	}
+	h.announceConnected(client.UserID, p.sessions)
+}
+
+func (h *Hub) disconnect(client *Client) {
+	p, ok := h.presence[client.UserID]
+	if !ok {
+		return
+	}
+	p.connections--
+	p.sessions[client.SessionID]--
+	if p.sessions[client.SessionID] <= 0 {
+		delete(p.sessions, client.SessionID)
+	}
+
+	if p.connections <= 0 {
+		delete(h.presence, client.UserID)
+	}
+	h.announceConnected(client.UserID, p.sessions)
+}
+
+// announceConnected tells every node, this one included, how many
+// connections the user has open here per session. No sessions means the
+// user's last connection here closed.
+func (h *Hub) announceConnected(userID uint, sessions map[string]int) {
+	event := &presenceEvent{
+		Type:      presenceEventConnected,
+		Node:      h.nodeID,
+		UserID:    userID,
+		Connected: len(sessions) > 0,
+		Sessions:  make(map[string]int, len(sessions)),
+	}
+	for sessionID, n := range sessions {
+		event.Sessions[sessionID] = n
+	}
+	h.presenceQueue.push(func() {
+		h.publishPresence(event)
+	})
+}
+
+// joinCluster subscribes to the presence room and asks the other nodes which
+// users they have connected. It runs on the presence queue.
+func (h *Hub) joinCluster() {
+	if err := h.broker.Subscribe(presenceRoom, h.receivePresence); err != nil {
+		log.Printf("❌ Error subscribing to presence: %v", err)
+		return
+	}
+	h.publishPresence(&presenceEvent{Type: presenceEventHello, Node: h.nodeID})
+}
+
+// publishPresence runs on the presence queue.
+func (h *Hub) publishPresence(event *presenceEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling presence event: %v", err)
+		return
+	}
+
+	if err := h.broker.Publish(presenceRoom, payload); err != nil {
+		log.Printf("❌ Error publishing presence: %v", err)
+		// Other nodes miss the change, but this node still tracks its own
+		// users.
+		if event.Type == presenceEventConnected {
+			h.applyPresence(event)
+		}
+	}
+}
+
+// receivePresence is the broker callback for the presence room. Events are
+// applied on the presence queue, so they stay in the order they arrived in.
+func (h *Hub) receivePresence(payload []byte) {
+	var event presenceEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		log.Printf("Error parsing presence event: %v", err)
+		return
+	}
+
+	h.presenceQueue.push(func() {
+		h.applyPresence(&event)
+	})
+}
+
+// applyPresence updates which nodes each user is connected to. Every node
+// sees the events in the same order, and only the node whose connections
+// changed persists a change, so a user going offline on one node while still
+// connected to another stays online.
+func (h *Hub) applyPresence(event *presenceEvent) {
+	if event.Type == presenceEventHello {
+		if event.Node != h.nodeID {
+			h.announceNodes()
+		}
+		return
+	}
+
+	h.nodesMu.Lock()
+	nodes, wasOnline := h.nodes[event.UserID]
+	if event.Connected {
+		if !wasOnline {
+			nodes = make(map[string]map[string]int)
+			h.nodes[event.UserID] = nodes
+		}
+		nodes[event.Node] = event.Sessions
+	} else if wasOnline {
+		delete(nodes, event.Node)
+		if len(nodes) == 0 {
+			delete(h.nodes, event.UserID)
+		}
+	}
+	_, isOnline := h.nodes[event.UserID]
+	h.nodesMu.Unlock()
+
+	if event.Node != h.nodeID || wasOnline == isOnline {
+		return
+	}
+
+	status := statusOffline
+	if isOnline {
+		status = statusOnline
+	}
+	h.persistStatus(event.UserID, status)
+}
+
+// announceNodes answers another node's hello with the users connected here.
+func (h *Hub) announceNodes() {
+	for userID, nodes := range h.nodes {
+		if sessions, ok := nodes[h.nodeID]; ok {
+			h.publishPresence(&presenceEvent{
+				Type:      presenceEventConnected,
+				Node:      h.nodeID,
+				UserID:    userID,
+				Connected: true,
+				Sessions:  sessions,
+			})
+		}
+	}
+}
+
+func (h *Hub) updatePresence(update *PresenceUpdate) {
+	if update.Status != statusOnline && update.Status != statusAway {
+		return
+	}
+
+	p, ok := h.presence[update.Client.UserID]
+	if !ok || p.status == update.Status {
+		return
+	}
+
+	h.setStatus(update.Client, p, update.Status)
+}
+
+// setStatus records the new status in memory right away and persists and
+// announces it on the presence queue, which keeps updates in order.
+func (h *Hub) setStatus(client *Client, p *userPresence, status string) {
+	p.status = status
+
+	userID := client.UserID
+	h.presenceQueue.push(func() {
+		h.persistStatus(userID, status)
+	})
+}
+
+// persistStatus announces the change with the stored username; the one on
+// the client comes from the handshake and is not authenticated.
+func (h *Hub) persistStatus(userID uint, status string) {
+	username, roomIDs, err := h.presenceStore.SetPresence(userID, status)
+	if err != nil {
+		log.Printf("❌ Error updating presence for user %d: %v", userID, err)
+		return
+	}
+
+	for _, roomID := range roomIDs {
+		h.broadcastToRoom(roomID, &OutgoingMessage{
+			Type:      "presence_changed",
+			Content:   status,
+			RoomID:    roomID,
+			UserID:    userID,
+			Username:  username,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// presenceQueue is an unbounded FIFO run by one goroutine. Unlike the worker
+// pool it never refuses work: a dropped offline transition would leave the
+// user online indefinitely, and Run cannot block waiting for room either.
+type presenceQueue struct {
+	mu    sync.Mutex
+	jobs  []func()
+	ready chan struct{}
+}
+
+func newPresenceQueue() *presenceQueue {
+	return &presenceQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *presenceQueue) push(job func()) {
+	q.mu.Lock()
+	q.jobs = append(q.jobs, job)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *presenceQueue) run() {
+	for range q.ready {
+		q.mu.Lock()
+		jobs := q.jobs
+		q.jobs = nil
+		q.mu.Unlock()
+
+		for _, job := range jobs {
+			job()
+		}
+	}
+}
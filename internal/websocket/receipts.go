@@ -0,0 +1,51 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const receiptDebounce = 2 * time.Second
+
+type receiptKey struct {
+	roomID string
+	userID uint
+}
+
+// receiptDebouncer coalesces read receipts per user and room so a client
+// scrolling through history produces one read_receipt event rather than one
+// per message.
+type receiptDebouncer struct {
+	mu      sync.Mutex
+	pending map[receiptKey]*OutgoingMessage
+	flush   func(*OutgoingMessage)
+}
+
+func newReceiptDebouncer(flush func(*OutgoingMessage)) *receiptDebouncer {
+	return &receiptDebouncer{
+		pending: make(map[receiptKey]*OutgoingMessage),
+		flush:   flush,
+	}
+}
+
+func (d *receiptDebouncer) add(receipt *OutgoingMessage) {
+	key := receiptKey{roomID: receipt.RoomID, userID: receipt.UserID}
+
+	d.mu.Lock()
+	_, scheduled := d.pending[key]
+	d.pending[key] = receipt
+	d.mu.Unlock()
+
+	if scheduled {
+		return
+	}
+
+	time.AfterFunc(receiptDebounce, func() {
+		d.mu.Lock()
+		latest := d.pending[key]
+		delete(d.pending, key)
+		d.mu.Unlock()
+
+		d.flush(latest)
+	})
+}
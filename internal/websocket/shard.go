@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"sync"
+)
+
+const shardQueueSize = 1024
+
+type delivery struct {
+	roomID  string
+	payload []byte
+}
+
+// shard owns a slice of the hub's rooms. Its goroutine fans payloads out to
+// the shard's local subscribers, so one busy room only delays rooms that
+// hash to the same shard.
+type shard struct {
+	hub        *Hub
+	mu         sync.RWMutex
+	rooms      map[string]map[*Client]bool
+	deliveries chan delivery
+
+	// subMu guards refs, the number of references held on each room's
+	// broker subscription.
+	subMu sync.Mutex
+	refs  map[string]int
+}
+
+func newShard(hub *Hub) *shard {
+	return &shard{
+		hub:        hub,
+		rooms:      make(map[string]map[*Client]bool),
+		deliveries: make(chan delivery, shardQueueSize),
+		refs:       make(map[string]int),
+	}
+}
+
+func (s *shard) run() {
+	for d := range s.deliveries {
+		s.fanOut(d)
+	}
+}
+
+func (s *shard) fanOut(d delivery) {
+	var event struct {
+		Type   string `json:"type"`
+		Seq    int64  `json:"seq"`
+		UserID uint   `json:"user_id"`
+	}
+	if err := json.Unmarshal(d.payload, &event); err != nil {
+		log.Printf("Error parsing room event: %v", err)
+		return
+	}
+
+	var removed []*Client
+
+	s.mu.RLock()
+	for client := range s.rooms[d.roomID] {
+		client.deliver(d.roomID, event.Seq, d.payload)
+		if event.Type == "member_removed" && client.UserID == event.UserID {
+			removed = append(removed, client)
+		}
+	}
+	s.mu.RUnlock()
+
+	// Detaching here, before the shard moves on, guarantees a removed user
+	// receives nothing after member_removed. Releasing the subscription and
+	// announcing user_left publish to the broker, which may deliver back
+	// into this shard, so they happen on another goroutine.
+	for _, client := range removed {
+		if s.hub.detach(client, d.roomID) {
+			go s.hub.unsubscribed(client, d.roomID)
+		}
+	}
+}
+
+// acquire takes a reference on the shard's broker subscription for a room,
+// subscribing on the first one. Every local subscriber holds a reference from
+// before it is added until after it is removed, so the broker subscription
+// outlives the room's last subscriber. Broker calls can be network I/O; they
+// are serialized by subMu and never made under the hub or shard lock.
+func (s *shard) acquire(roomID string) error {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.refs[roomID] == 0 {
+		err := s.hub.broker.Subscribe(roomID, func(payload []byte) {
+			s.deliveries <- delivery{roomID: roomID, payload: payload}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.refs[roomID]++
+	return nil
+}
+
+// release drops a reference taken by acquire, unsubscribing from the broker
+// with the last one.
+func (s *shard) release(roomID string) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.refs[roomID]--; s.refs[roomID] > 0 {
+		return
+	}
+	delete(s.refs, roomID)
+	if err := s.hub.broker.Unsubscribe(roomID); err != nil {
+		log.Printf("❌ Error unsubscribing from broker: %v", err)
+	}
+}
+
+// add makes a client a local subscriber of a room and returns the number of
+// local subscribers. The caller must hold a reference from acquire.
+func (s *shard) add(client *Client, roomID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients, ok := s.rooms[roomID]
+	if !ok {
+		clients = make(map[*Client]bool)
+		s.rooms[roomID] = clients
+	}
+	clients[client] = true
+
+	return len(clients)
+}
+
+// remove stops delivering a room to a client. The caller releases the
+// client's reference once it no longer holds the hub lock.
+func (s *shard) remove(client *Client, roomID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients, ok := s.rooms[roomID]
+	if !ok {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(s.rooms, roomID)
+	}
+}
+
+func partition(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,40 @@
+package websocket
+
+import "log"
+
+// workerPool runs hub work that touches the database, such as persisting
+// messages and authorizing subscriptions, off the hub and shard goroutines.
+// Jobs are partitioned by key onto bounded per-worker queues so work for the
+// same room is still processed in order.
+type workerPool struct {
+	queues []chan func()
+}
+
+func newWorkerPool(workers, queueSize int) *workerPool {
+	p := &workerPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+	}
+	return p
+}
+
+func (p *workerPool) start() {
+	for _, queue := range p.queues {
+		go func(queue chan func()) {
+			for job := range queue {
+				job()
+			}
+		}(queue)
+	}
+}
+
+// submit queues a job and reports false when the worker for key is full.
+func (p *workerPool) submit(key string, job func()) bool {
+	select {
+	case p.queues[partition(key, len(p.queues))] <- job:
+		return true
+	default:
+		log.Printf("❌ Worker queue full, dropping job for %s", key)
+		return false
+	}
+}